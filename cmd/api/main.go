@@ -1,32 +1,78 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
 
+	"github.com/callMe-Root/unbound-control-api/internal/admin"
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/changes"
 	"github.com/callMe-Root/unbound-control-api/internal/config"
 	"github.com/callMe-Root/unbound-control-api/internal/handler"
+	"github.com/callMe-Root/unbound-control-api/internal/metrics"
 	"github.com/callMe-Root/unbound-control-api/internal/middleware"
 	"github.com/callMe-Root/unbound-control-api/internal/server"
+	"github.com/callMe-Root/unbound-control-api/internal/tracing"
 	"github.com/callMe-Root/unbound-control-api/internal/unbound"
 	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+	"github.com/gorilla/mux"
 )
 
 func main() {
+	configPath := flag.String("config", defaultConfigPath(), "path of the configuration file")
+	hashKey := flag.Bool("hash-key", false, "read an API key from stdin, print its key_hash and exit")
+	flag.Parse()
+
+	if *hashKey {
+		printKeyHash()
+		return
+	}
+
 	// Load configuration
-	cfg, err := config.LoadConfig("config.yaml")
+	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
 	// Initialize logger
-	logger.Initialize(cfg.Logging.Level, cfg.Logging.UseSyslog, cfg.Logging.AppName)
+	logger.Initialize(logger.Config{
+		Level:     cfg.Logging.Level,
+		Format:    cfg.Logging.Format,
+		Outputs:   cfg.Logging.Outputs,
+		UseSyslog: cfg.Logging.UseSyslog,
+		AppName:   cfg.Logging.AppName,
+		File: logger.FileConfig{
+			Path:           cfg.Logging.File.Path,
+			MaxSizeMB:      cfg.Logging.File.MaxSizeMB,
+			RotateInterval: cfg.Logging.File.RotateInterval,
+			MaxAge:         cfg.Logging.File.MaxAge,
+			MaxBackups:     cfg.Logging.File.MaxBackups,
+			Compress:       cfg.Logging.File.Compress,
+		},
+	})
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Initialize(context.Background(), cfg.Tracing, cfg.Logging.AppName)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	// Create Unbound client
-	client, err := unbound.NewClient(cfg.Unbound.ControlSocket)
+	clientOpts := unbound.OptionsFromConfig(cfg.Unbound)
+	clientOpts.Logger = logger.Get()
+	client, err := unbound.NewClient(cfg.Unbound.ControlSocket, clientOpts)
 	if err != nil {
 		log.Fatalf("Failed to create Unbound client: %v", err)
 	}
-	defer client.Close()
 
 	// Create server
 	var certFile, keyFile string
@@ -34,27 +80,249 @@ func main() {
 		certFile = cfg.Server.CertFile
 		keyFile = cfg.Server.KeyFile
 	}
-	srv := server.New(cfg.Server.Host, cfg.Server.Port, certFile, keyFile, cfg, client)
+	// Load API keys
+	keys, err := auth.NewKeySet(cfg.Security)
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+
+	// Set up bearer token authentication
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.Security.JWT.Enabled {
+		jwtVerifier, err = auth.NewJWTVerifier(context.Background(), cfg.Security.JWT)
+		if err != nil {
+			log.Fatalf("Failed to set up JWT authentication: %v", err)
+		}
+	}
+
+	// Set up signed request authentication
+	var signatures *auth.SignatureVerifier
+	if cfg.Security.Signing.Enabled {
+		signatures, err = auth.NewSignatureVerifier(cfg.Security.Signing)
+		if err != nil {
+			log.Fatalf("Failed to load signing keys: %v", err)
+		}
+	}
+
+	// Map client certificates to identities when they are verified
+	var certMapper *auth.CertMapper
+	if cfg.Server.ClientAuth != "" && cfg.Server.ClientAuth != "none" {
+		certMapper, err = auth.NewCertMapper(cfg.Security.ClientCerts)
+		if err != nil {
+			log.Fatalf("Failed to load client certificate mappings: %v", err)
+		}
+	}
 
-	// Add logging middleware
-	srv.Router().Use(middleware.LoggingMiddleware())
+	authn := middleware.Authenticators{Keys: keys, JWT: jwtVerifier, Signatures: signatures, ClientCerts: certMapper}
+	// Resolve client addresses behind trusted proxies and load the address lists
+	sources, err := middleware.NewSourcePolicy(cfg.Security)
+	if err != nil {
+		log.Fatalf("Failed to load source address policy: %v", err)
+	}
+
+	// Open the audit log of state-changing requests
+	var auditLog *audit.Log
+	if cfg.Audit.Enabled {
+		path := cfg.Audit.Path
+		if path == "" {
+			path = audit.DefaultPath
+		}
+		auditLog, err = audit.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer auditLog.Close()
+	}
+
+	// Allow browser pages from the configured origins to call the API
+	var cors *middleware.CORSPolicy
+	if cfg.CORS.Enabled {
+		cors, err = middleware.NewCORSPolicy(cfg.CORS)
+		if err != nil {
+			log.Fatalf("Failed to load CORS settings: %v", err)
+		}
+	}
+
+	limiter := middleware.NewRateLimiter(cfg.RateLimit)
+
+	srv := server.New(cfg.Server.Host, cfg.Server.Port, certFile, keyFile, *configPath, cfg, client, authn, sources, cors, limiter)
+	// The client is replaced when the control socket changes on reload, so
+	// everything below reads the current one from the server
+	defer func() { srv.Client().Close() }()
+
+	// Add request ID, tracing and logging middleware
+	srv.Router().Use(middleware.RequestID())
+	srv.Router().Use(middleware.ClientIP(sources))
+	srv.Router().Use(middleware.Tracing())
+	srv.Router().Use(middleware.Metrics())
+	redaction := middleware.RedactionRules{
+		Headers:      cfg.Logging.Redact.Headers,
+		Fields:       cfg.Logging.Redact.Fields,
+		MaxBodyBytes: cfg.Logging.Redact.MaxBodyBytes,
+	}
+	srv.Router().Use(middleware.LoggingMiddleware(redaction))
+	if cors != nil {
+		// Preflight requests under /api/v1 match this route so the CORS
+		// middleware runs for them, ahead of authentication on the API
+		// routes. Any OPTIONS request there is answered with 204; on other
+		// paths OPTIONS is routed as usual and gets 404 or 405.
+		srv.Router().Use(middleware.CORS(cors))
+		isOptions := func(r *http.Request, _ *mux.RouteMatch) bool { return r.Method == http.MethodOptions }
+		srv.Router().PathPrefix("/api/v1/").MatcherFunc(isOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
 
 	// Create handlers
-	unboundHandler := handler.NewUnboundHandler(client)
+	unboundHandler := handler.NewUnboundHandler(srv.Client, cfg.Unbound.ConfigFile)
+	healthHandler := handler.NewHealthHandler(srv.Client, srv.Config)
 
-	// API routes with authentication and rate limiting
+	// Unauthenticated probes for load balancers and Kubernetes
+	srv.Router().HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	srv.Router().HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+
+	// API routes with source address checks, authentication and rate limiting
 	api := srv.Router().PathPrefix("/api/v1").Subrouter()
-	api.Use(middleware.APIKeyAuth(cfg.Security.APIKey))
-	api.Use(middleware.RateLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.BurstSize))
+	api.Use(middleware.SourceFilter(sources))
+	api.Use(middleware.Authenticate(authn))
+	if auditLog != nil {
+		api.Use(middleware.Audit(auditLog, redaction))
+	}
+	api.Use(middleware.RateLimit(limiter))
+
+	// Dangerous operations need a second person's approval when enabled. The
+	// requester needs scope, and so does the approver.
+	gate := func(op, scope string, match func(*http.Request) bool, next http.HandlerFunc) http.Handler {
+		return middleware.RequireScope(scope, next)
+	}
+	var changesHandler *handler.ChangesHandler
+	var removesMany func(*http.Request) bool
+	if cfg.Approvals.Enabled {
+		manager, err := changes.NewManager(cfg.Approvals)
+		if err != nil {
+			log.Fatalf("Failed to set up approvals: %v", err)
+		}
+		changesHandler = handler.NewChangesHandler(manager)
+		gate = func(op, scope string, match func(*http.Request) bool, next http.HandlerFunc) http.Handler {
+			return middleware.RequireScope(scope, changesHandler.Gate(op, scope, match, next))
+		}
+		removesMany = func(r *http.Request) bool {
+			return manager.Bulk(len(r.URL.Query()["name"]))
+		}
+	}
+
+	// Unbound control routes, each requiring a scope
+	api.Handle("/status", middleware.RequireScope(auth.ScopeStatusRead, unboundHandler.Status)).Methods("GET")
+	api.Handle("/health", middleware.RequireScope(auth.ScopeStatusRead, unboundHandler.Health)).Methods("GET")
+	api.Handle("/reload", gate(changes.OpReload, auth.ScopeServerReload, dropsCache, unboundHandler.Reload)).Methods("POST")
+	api.Handle("/stop", gate(changes.OpStop, auth.ScopeServerStop, nil, unboundHandler.Stop)).Methods("POST")
+	api.Handle("/flush", middleware.RequireScope(auth.ScopeCacheFlush, unboundHandler.Flush)).Methods("DELETE")
+	api.Handle("/flush/zone", gate(changes.OpFlushZoneRoot, auth.ScopeCacheFlush, flushesRoot, unboundHandler.FlushZone)).Methods("DELETE")
+	api.Handle("/stats", middleware.RequireScope(auth.ScopeStatsRead, unboundHandler.Stats)).Methods("GET")
+
+	// Prometheus metrics for Unbound and the API itself
+	metrics.Registry.MustRegister(unbound.NewStatsCollector(srv.Client))
+	api.Handle("/metrics", middleware.RequireScope(auth.ScopeStatsRead, metrics.Handler().ServeHTTP)).Methods("GET")
+
+	// Local zone and local data routes, optionally scoped to a view
+	api.Handle("/local_zones", middleware.RequireScope(auth.ScopeZonesRead, unboundHandler.ListLocalZones)).Methods("GET")
+	api.Handle("/local_zones", middleware.RequireScope(auth.ScopeZonesWrite, unboundHandler.AddLocalZone)).Methods("POST")
+	api.Handle("/local_zones", gate(changes.OpRemoveLocalZone, auth.ScopeZonesWrite, removesMany, unboundHandler.RemoveLocalZone)).Methods("DELETE")
+	api.Handle("/local_data", middleware.RequireScope(auth.ScopeZonesRead, unboundHandler.ListLocalData)).Methods("GET")
+	api.Handle("/local_data", middleware.RequireScope(auth.ScopeZonesWrite, unboundHandler.AddLocalData)).Methods("POST")
+	api.Handle("/local_data", gate(changes.OpRemoveLocalData, auth.ScopeZonesWrite, removesMany, unboundHandler.RemoveLocalData)).Methods("DELETE")
+	api.Handle("/views", middleware.RequireScope(auth.ScopeZonesRead, unboundHandler.ListViews)).Methods("GET")
+
+	// Rate limit inspection routes
+	api.Handle("/ratelimit", middleware.RequireScope(auth.ScopeStatsRead, unboundHandler.RateLimits)).Methods("GET")
+	api.Handle("/ratelimit/domains", middleware.RequireScope(auth.ScopeStatsRead, unboundHandler.DomainRateLimits)).Methods("GET")
+	api.Handle("/ratelimit/ips", middleware.RequireScope(auth.ScopeStatsRead, unboundHandler.IPRateLimits)).Methods("GET")
+
+	// In-flight query inspection
+	api.Handle("/requestlist", middleware.RequireScope(auth.ScopeStatsRead, unboundHandler.RequestList)).Methods("GET")
+
+	// DNS cookie secret rotation
+	api.Handle("/cookie_secrets", middleware.RequireScope(auth.ScopeCookiesRead, unboundHandler.CookieSecrets)).Methods("GET")
+	api.Handle("/cookie_secrets", middleware.RequireScope(auth.ScopeCookiesWrite, unboundHandler.AddCookieSecret)).Methods("POST")
+	api.Handle("/cookie_secrets", middleware.RequireScope(auth.ScopeCookiesWrite, unboundHandler.DropCookieSecret)).Methods("DELETE")
+	api.Handle("/cookie_secrets/activate", middleware.RequireScope(auth.ScopeCookiesWrite, unboundHandler.ActivateCookieSecret)).Methods("POST")
+
+	// API key lifecycle management, available with a key store
+	if store := keys.Store(); store != nil {
+		keysHandler := handler.NewKeysHandler(store)
+		api.Handle("/keys", middleware.RequireScope(auth.ScopeKeysManage, keysHandler.ListKeys)).Methods("GET")
+		api.Handle("/keys", middleware.RequireScope(auth.ScopeKeysManage, keysHandler.CreateKey)).Methods("POST")
+		api.Handle("/keys/{id}/rotate", middleware.RequireScope(auth.ScopeKeysManage, keysHandler.RotateKey)).Methods("POST")
+		api.Handle("/keys/{id}/expire", middleware.RequireScope(auth.ScopeKeysManage, keysHandler.ExpireKey)).Methods("POST")
+		api.Handle("/keys/{id}", middleware.RequireScope(auth.ScopeKeysManage, keysHandler.RevokeKey)).Methods("DELETE")
+	}
+
+	// Audit log queries and chain verification
+	if auditLog != nil {
+		auditHandler := handler.NewAuditHandler(auditLog)
+		api.Handle("/audit", middleware.RequireScope(auth.ScopeAuditRead, auditHandler.Records)).Methods("GET")
+		api.Handle("/audit/verify", middleware.RequireScope(auth.ScopeAuditRead, auditHandler.Verify)).Methods("GET")
+	}
+
+	// Change requests awaiting approval
+	if changesHandler != nil {
+		api.Handle("/changes", middleware.RequireScope(auth.ScopeChangesRead, changesHandler.ListChanges)).Methods("GET")
+		api.Handle("/changes/{id}", middleware.RequireScope(auth.ScopeChangesRead, changesHandler.GetChange)).Methods("GET")
+		api.Handle("/changes/{id}/approve", middleware.RequireScope(auth.ScopeChangesApprove, changesHandler.ApproveChange)).Methods("POST")
+		api.Handle("/changes/{id}/reject", middleware.RequireScope(auth.ScopeChangesRead, changesHandler.RejectChange)).Methods("POST")
+	}
 
-	// Unbound control routes
-	api.HandleFunc("/status", unboundHandler.Status).Methods("GET")
-	api.HandleFunc("/reload", unboundHandler.Reload).Methods("POST")
-	api.HandleFunc("/flush", unboundHandler.Flush).Methods("DELETE")
-	api.HandleFunc("/stats", unboundHandler.Stats).Methods("GET")
+	// Start the admin listener for pprof and runtime information
+	if cfg.Admin.Enabled {
+		adminServer := admin.New(cfg.Admin, srv.Config)
+		if err := adminServer.Start(); err != nil {
+			log.Fatalf("Failed to start admin listener: %v", err)
+		}
+		defer adminServer.Close()
+	}
 
 	// Start server
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
+
+// defaultConfigPath returns the configuration file named by the environment,
+// or config.yaml in the working directory
+func defaultConfigPath() string {
+	if path := os.Getenv(config.EnvPrefix + "_CONFIG"); path != "" {
+		return path
+	}
+	return config.DefaultPath
+}
+
+// printKeyHash prints the key_hash for the API key read from stdin, so keys
+// can be configured without storing them in plaintext
+func printKeyHash() {
+	// A missing trailing newline is fine, only an empty key is an error
+	key, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	key = strings.TrimRight(key, "\r\n")
+	if key == "" {
+		log.Fatal("No API key given on stdin")
+	}
+
+	hash, err := auth.HashKey(key)
+	if err != nil {
+		log.Fatalf("Failed to hash API key: %v", err)
+	}
+	fmt.Println(hash)
+}
+
+// dropsCache reports whether a reload is a full one, dropping the cache.
+// Invalid keep_cache values are left for the handler to reject.
+func dropsCache(r *http.Request) bool {
+	value := r.URL.Query().Get("keep_cache")
+	keep, err := strconv.ParseBool(value)
+	return value == "" || (err == nil && !keep)
+}
+
+// flushesRoot reports whether a zone flush targets the root, emptying the
+// whole cache
+func flushesRoot(r *http.Request) bool {
+	return r.URL.Query().Get("domain") == "."
+}
@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+)
+
+const (
+	// DefaultListen is used when admin.listen is not set
+	DefaultListen = "127.0.0.1:6060"
+
+	// unixPrefix selects a unix socket in admin.listen
+	unixPrefix = "unix:"
+)
+
+// Server is the debug/admin listener. It has no authentication, so it must
+// only be reachable from the host: localhost or a unix socket.
+type Server struct {
+	httpServer  *http.Server
+	listen      string
+	allowRemote bool
+	config      func() *config.Config
+	started     time.Time
+}
+
+// New creates the admin server listening on cfg.Listen. current returns the
+// configuration in effect, so /debug/config reflects reloads.
+func New(cfg config.AdminConfig, current func() *config.Config) *Server {
+	listen := cfg.Listen
+	if listen == "" {
+		listen = DefaultListen
+	}
+
+	s := &Server{
+		listen:      listen,
+		allowRemote: cfg.AllowRemote,
+		config:      current,
+		started:     time.Now(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/buildinfo", s.buildInfo)
+	mux.HandleFunc("/debug/runtime", s.runtimeInfo)
+	mux.HandleFunc("/debug/config", s.effectiveConfig)
+
+	s.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// checkListen refuses TCP addresses other than loopback ones, including
+// host names besides localhost and addresses without a host, unless
+// admin.allow_remote is set
+func checkListen(listen string, allowRemote bool) error {
+	if strings.HasPrefix(listen, unixPrefix) || allowRemote {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && addr.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%s is not a loopback address; the admin listener has no authentication, set admin.allow_remote to expose it anyway", listen)
+}
+
+// listener opens the TCP address or unix socket named by admin.listen
+func (s *Server) listener() (net.Listener, error) {
+	if path, ok := strings.CutPrefix(s.listen, unixPrefix); ok {
+		// Remove a socket left behind by a previous run
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+		l, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+		}
+		return l, nil
+	}
+	return net.Listen("tcp", s.listen)
+}
+
+// Start serves the admin endpoints in the background
+func (s *Server) Start() error {
+	if err := checkListen(s.listen, s.allowRemote); err != nil {
+		return err
+	}
+	l, err := s.listener()
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.listen, err)
+	}
+
+	log := logger.Get()
+	log.Info().Str("listen", s.listen).Msg("starting admin listener")
+
+	go func() {
+		if err := s.httpServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("admin listener stopped")
+		}
+	}()
+	return nil
+}
+
+// Close stops the admin listener
+func (s *Server) Close() error {
+	return s.httpServer.Close()
+}
+
+func (s *Server) buildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		http.Error(w, "build information not available", http.StatusNotFound)
+		return
+	}
+
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"go_version": info.GoVersion,
+		"path":       info.Path,
+		"version":    info.Main.Version,
+		"settings":   settings,
+	})
+}
+
+func (s *Server) runtimeInfo(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeJSON(w, map[string]interface{}{
+		"uptime":     time.Since(s.started).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"num_cpu":    runtime.NumCPU(),
+		"memory": map[string]uint64{
+			"heap_alloc":  mem.HeapAlloc,
+			"heap_inuse":  mem.HeapInuse,
+			"sys":         mem.Sys,
+			"num_gc":      uint64(mem.NumGC),
+			"total_alloc": mem.TotalAlloc,
+		},
+	})
+}
+
+func (s *Server) effectiveConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, config.Redacted(s.config()))
+}
+
+func writeJSON(w http.ResponseWriter, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payload)
+}
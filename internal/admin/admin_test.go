@@ -0,0 +1,28 @@
+package admin
+
+import "testing"
+
+func TestCheckListen(t *testing.T) {
+	tests := []struct {
+		listen      string
+		allowRemote bool
+		ok          bool
+	}{
+		{"127.0.0.1:6060", false, true},
+		{"[::1]:6060", false, true},
+		{"localhost:6060", false, true},
+		{"unix:/run/admin.sock", false, true},
+		{"0.0.0.0:6060", false, false},
+		{":6060", false, false},
+		{"[::]:6060", false, false},
+		{"192.0.2.1:6060", false, false},
+		{"admin.example.com:6060", false, false},
+		{"0.0.0.0:6060", true, true},
+	}
+	for _, tt := range tests {
+		err := checkListen(tt.listen, tt.allowRemote)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkListen(%q, %v) = %v, want ok %v", tt.listen, tt.allowRemote, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Outcomes recorded for an operation
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Record is one audited operation. Records are chained: PrevHash is the Hash
+// of the record before it, and Hash covers every other field.
+type Record struct {
+	Seq        uint64                 `json:"seq"`
+	Time       time.Time              `json:"time"`
+	Identity   string                 `json:"identity,omitempty"`
+	AuthMethod string                 `json:"auth_method,omitempty"`
+	SourceIP   string                 `json:"source_ip"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Method     string                 `json:"method"`
+	Route      string                 `json:"route"`
+	Path       string                 `json:"path"`
+	Commands   []string               `json:"commands,omitempty"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Status     int                    `json:"status"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+	Before     interface{}            `json:"before,omitempty"`
+	After      interface{}            `json:"after,omitempty"`
+	PrevHash   string                 `json:"prev_hash"`
+	Hash       string                 `json:"hash,omitempty"`
+}
+
+// Entry collects what happens while a request is handled: the Unbound
+// commands it sends and the state before and after the change
+type Entry struct {
+	mu       sync.Mutex
+	commands []string
+	before   interface{}
+	after    interface{}
+}
+
+// entryKey is the context key for the request's audit entry
+type entryKey struct{}
+
+// NewContext returns a copy of ctx carrying a new audit entry
+func NewContext(ctx context.Context) (context.Context, *Entry) {
+	entry := &Entry{}
+	return context.WithValue(ctx, entryKey{}, entry), entry
+}
+
+func fromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(entryKey{}).(*Entry)
+	return entry
+}
+
+// Enabled reports whether the request in ctx is audited, so handlers can
+// skip collecting state that would not be recorded
+func Enabled(ctx context.Context) bool {
+	return fromContext(ctx) != nil
+}
+
+// RecordCommand notes a control command sent on behalf of the request. It
+// does nothing when the request is not audited.
+func RecordCommand(ctx context.Context, cmd string) {
+	if entry := fromContext(ctx); entry != nil {
+		entry.mu.Lock()
+		entry.commands = append(entry.commands, cmd)
+		entry.mu.Unlock()
+	}
+}
+
+// RecordState notes the state before and after the change. Either may be nil
+// when it is not known.
+func RecordState(ctx context.Context, before, after interface{}) {
+	if entry := fromContext(ctx); entry != nil {
+		entry.mu.Lock()
+		entry.before, entry.after = before, after
+		entry.mu.Unlock()
+	}
+}
+
+// Fill copies the collected commands and state into rec
+func (e *Entry) Fill(rec *Record) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rec.Commands = e.commands
+	rec.Before = e.before
+	rec.After = e.after
+}
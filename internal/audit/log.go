@@ -0,0 +1,278 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPath is used when audit.path is not set
+	DefaultPath = "audit.log"
+
+	// genesisHash is the PrevHash of the first record
+	genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	// maxLineSize bounds a single record when reading the log back
+	maxLineSize = 1 << 20
+
+	// DefaultQueryLimit and MaxQueryLimit bound the records returned by Query
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+// hashSuffix is how the hash is appended to a record's JSON
+var hashSuffix = []byte(`,"hash":"`)
+
+// Log is an append-only audit log of JSON lines. Each line carries the hash
+// of the line before it, so removing or editing a record breaks the chain.
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	seq  uint64
+	last string
+}
+
+// Open opens the audit log at path, creating it if needed, and continues the
+// hash chain from its last record. A log whose chain does not verify is
+// refused so tampering is not silently built upon.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+
+	result, err := l.Verify()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil && !result.Valid {
+		return nil, fmt.Errorf("audit log %s failed verification at record %d: %s", path, result.BrokenAt, result.Reason)
+	}
+	l.seq = result.Records
+	l.last = result.LastHash
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = f
+	return l, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Append assigns the next sequence number, chains rec to the previous record
+// and writes it to disk before returning
+func (l *Log) Append(rec Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec.Seq = l.seq + 1
+	rec.PrevHash = l.last
+	rec.Hash = ""
+
+	line, hash, err := encode(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+
+	l.seq = rec.Seq
+	l.last = hash
+	return nil
+}
+
+// encode returns the line for rec and its hash. The hash covers the record's
+// JSON without the hash field, which is then appended as the last field.
+func encode(rec Record) ([]byte, string, error) {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	line := make([]byte, 0, len(body)+len(hashSuffix)+len(hash)+3)
+	line = append(line, body[:len(body)-1]...)
+	line = append(line, hashSuffix...)
+	line = append(line, hash...)
+	line = append(line, '"', '}', '\n')
+	return line, hash, nil
+}
+
+// split separates a line into the hashed body and the stored hash
+func split(line []byte) ([]byte, string, bool) {
+	i := bytes.LastIndex(line, hashSuffix)
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+		return nil, "", false
+	}
+	hash := string(line[i+len(hashSuffix) : len(line)-2])
+	body := append(append([]byte(nil), line[:i]...), '}')
+	return body, hash, true
+}
+
+// VerifyResult reports the state of the hash chain
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Records  uint64 `json:"records"`
+	LastHash string `json:"last_hash"`
+	BrokenAt uint64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Verify reads the whole log and checks every record's hash, sequence number
+// and link to the record before it
+func (l *Log) Verify() (VerifyResult, error) {
+	result := VerifyResult{Valid: true, LastHash: genesisHash}
+	err := l.scan(func(line []byte, rec *Record) bool {
+		n := result.Records + 1
+		fail := func(reason string) bool {
+			result.Valid, result.BrokenAt, result.Reason = false, n, reason
+			return false
+		}
+
+		if rec == nil {
+			return fail("record is not valid JSON")
+		}
+		body, hash, ok := split(line)
+		if !ok {
+			return fail("record has no trailing hash")
+		}
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != hash || rec.Hash != hash {
+			return fail("hash does not match record")
+		}
+		if rec.Seq != n {
+			return fail(fmt.Sprintf("expected sequence %d, found %d", n, rec.Seq))
+		}
+		if rec.PrevHash != result.LastHash {
+			return fail("record does not link to the previous record")
+		}
+
+		result.Records = n
+		result.LastHash = hash
+		return true
+	})
+	return result, err
+}
+
+// Filter selects records in Query. Zero fields match everything.
+type Filter struct {
+	Since    time.Time
+	Until    time.Time
+	Identity string
+	Route    string
+	Outcome  string
+	Limit    int
+}
+
+func (f Filter) matches(rec *Record) bool {
+	switch {
+	case !f.Since.IsZero() && rec.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && rec.Time.After(f.Until):
+		return false
+	case f.Identity != "" && rec.Identity != f.Identity:
+		return false
+	case f.Route != "" && !strings.HasPrefix(rec.Route, f.Route):
+		return false
+	case f.Outcome != "" && rec.Outcome != f.Outcome:
+		return false
+	}
+	return true
+}
+
+// Query returns the most recent records matching f, oldest first
+func (l *Log) Query(f Filter) ([]Record, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+	limit = min(limit, MaxQueryLimit)
+
+	// Keep the last limit matches in a ring buffer
+	ring := make([]Record, 0, limit)
+	next := 0
+	err := l.scan(func(line []byte, rec *Record) bool {
+		if rec == nil || !f.matches(rec) {
+			return true
+		}
+		if len(ring) < limit {
+			ring = append(ring, *rec)
+		} else {
+			ring[next] = *rec
+			next = (next + 1) % limit
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(ring))
+	records = append(records, ring[next:]...)
+	return append(records, ring[:next]...), nil
+}
+
+// scan calls fn for every line of the log with the decoded record, or nil if
+// the line cannot be decoded, until fn returns false
+func (l *Log) scan(fn func(line []byte, rec *Record) bool) error {
+	// Hold the lock so a concurrent append is not read half written
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, maxLineSize)
+	for {
+		line, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return fmt.Errorf("audit record exceeds %d bytes", maxLineSize)
+		}
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				// A final line without newline is an interrupted write
+				fn(line, nil)
+				return nil
+			}
+			line = line[:len(line)-1]
+
+			var rec Record
+			decoded := &rec
+			if json.Unmarshal(line, &rec) != nil {
+				decoded = nil
+			}
+			if !fn(line, decoded) {
+				return nil
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestLog appends n records to a new log and returns its path and lines
+func writeTestLog(t *testing.T, n int) (string, [][]byte) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		rec := Record{Identity: "alice", Method: "POST", Route: "/api/v1/reload", Status: 200, Outcome: OutcomeSuccess}
+		if err := l.Append(rec); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	return path, lines[:len(lines)-1]
+}
+
+func TestSplit(t *testing.T) {
+	line, hash, err := encode(Record{Seq: 1, Method: "GET", PrevHash: genesisHash})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	body, got, ok := split(bytes.TrimSuffix(line, []byte("\n")))
+	if !ok || got != hash {
+		t.Fatalf("split = %q, %v, want %q", got, ok, hash)
+	}
+	if bytes.Contains(body, hashSuffix) {
+		t.Errorf("body still holds the hash: %s", body)
+	}
+
+	if _, _, ok := split([]byte(`{"seq":1,"method":"GET"}`)); ok {
+		t.Error("split accepted a line without a hash")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(lines [][]byte) [][]byte
+		brokenAt uint64
+	}{
+		{"intact", func(lines [][]byte) [][]byte { return lines }, 0},
+		{"tampered field", func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"identity":"alice"`), []byte(`"identity":"bob"`), 1)
+			return lines
+		}, 2},
+		{"tampered hash", func(lines [][]byte) [][]byte {
+			i := bytes.LastIndex(lines[1], hashSuffix) + len(hashSuffix)
+			lines[1][i] ^= 1
+			return lines
+		}, 2},
+		{"reordered", func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		}, 2},
+		{"removed", func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		}, 2},
+		{"interrupted write", func(lines [][]byte) [][]byte {
+			lines[2] = lines[2][:len(lines[2])/2]
+			return lines
+		}, 3},
+		{"not JSON", func(lines [][]byte) [][]byte {
+			lines[2] = []byte("garbage\n")
+			return lines
+		}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, lines := writeTestLog(t, 3)
+			if err := os.WriteFile(path, bytes.Join(tt.modify(lines), nil), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			result, err := (&Log{path: path}).Verify()
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if result.Valid != (tt.brokenAt == 0) || result.BrokenAt != tt.brokenAt {
+				t.Fatalf("Verify = valid %v, broken at %d (%s), want broken at %d", result.Valid, result.BrokenAt, result.Reason, tt.brokenAt)
+			}
+			if tt.brokenAt != 0 {
+				if _, err := Open(path); err == nil {
+					t.Error("Open accepted a broken log")
+				}
+			}
+		})
+	}
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	path, _ := writeTestLog(t, 2)
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := l.Append(Record{Method: "GET", Outcome: OutcomeSuccess}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	l.Close()
+
+	result, err := (&Log{path: path}).Verify()
+	if err != nil || !result.Valid || result.Records != 3 {
+		t.Fatalf("Verify = %+v, %v, want 3 valid records", result, err)
+	}
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Scopes guard individual API operations. Every route declares the scope it
+// requires and a caller needs that scope, or the "*" wildcard, to use it.
+const (
+	ScopeAll            = "*"
+	ScopeStatusRead     = "status:read"
+	ScopeStatsRead      = "stats:read"
+	ScopeCacheFlush     = "cache:flush"
+	ScopeServerReload   = "server:reload"
+	ScopeServerStop     = "server:stop"
+	ScopeZonesRead      = "zones:read"
+	ScopeZonesWrite     = "zones:write"
+	ScopeCookiesRead    = "cookies:read"
+	ScopeCookiesWrite   = "cookies:write"
+	ScopeCookieSecrets  = "cookies:secrets"
+	ScopeKeysManage     = "keys:manage"
+	ScopeAuditRead      = "audit:read"
+	ScopeChangesRead    = "changes:read"
+	ScopeChangesApprove = "changes:approve"
+)
+
+// Roles are named bundles of scopes
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// knownScopes lists every scope that may be granted
+var knownScopes = map[string]bool{
+	ScopeAll:            true,
+	ScopeStatusRead:     true,
+	ScopeStatsRead:      true,
+	ScopeCacheFlush:     true,
+	ScopeServerReload:   true,
+	ScopeServerStop:     true,
+	ScopeZonesRead:      true,
+	ScopeZonesWrite:     true,
+	ScopeCookiesRead:    true,
+	ScopeCookiesWrite:   true,
+	ScopeCookieSecrets:  true,
+	ScopeKeysManage:     true,
+	ScopeAuditRead:      true,
+	ScopeChangesRead:    true,
+	ScopeChangesApprove: true,
+}
+
+// roleScopes maps each role to the scopes it grants
+var roleScopes = map[string][]string{
+	RoleViewer: {
+		ScopeStatusRead, ScopeStatsRead, ScopeZonesRead, ScopeCookiesRead,
+		ScopeChangesRead,
+	},
+	RoleOperator: {
+		ScopeStatusRead, ScopeStatsRead, ScopeZonesRead, ScopeCookiesRead,
+		ScopeCacheFlush, ScopeServerReload, ScopeZonesWrite, ScopeCookiesWrite,
+		ScopeChangesRead,
+	},
+	RoleAdmin: {ScopeAll},
+}
+
+// Scopes is a set of granted scopes
+type Scopes map[string]bool
+
+// Has reports whether the set grants scope
+func (s Scopes) Has(scope string) bool {
+	return s[ScopeAll] || s[scope]
+}
+
+// Covers reports whether the set grants every scope in other, so a holder
+// of s cannot hand out more access than it has
+func (s Scopes) Covers(other Scopes) bool {
+	for scope := range other {
+		if !s.Has(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the granted scopes in sorted order
+func (s Scopes) List() []string {
+	list := make([]string, 0, len(s))
+	for scope := range s {
+		list = append(list, scope)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// ResolveScopes expands roles and explicit scopes into a scope set,
+// rejecting unknown names so typos do not silently grant nothing
+func ResolveScopes(roles, scopes []string) (Scopes, error) {
+	set := make(Scopes)
+	for _, role := range roles {
+		granted, ok := roleScopes[role]
+		if !ok {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		for _, scope := range granted {
+			set[scope] = true
+		}
+	}
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		set[scope] = true
+	}
+	return set, nil
+}
+
+// Identity is an authenticated caller
+type Identity struct {
+	// Name identifies the caller in logs, e.g. the API key name
+	Name string
+	// Method is how the caller authenticated, e.g. "api_key"
+	Method string
+	// Scopes are the permissions granted to the caller
+	Scopes Scopes
+	// Sources restricts the addresses the identity may be used from, nil for any
+	Sources *IPPolicy
+}
+
+// Has reports whether the identity is granted scope
+func (i *Identity) Has(scope string) bool {
+	return i != nil && i.Scopes.Has(scope)
+}
+
+// identityKey is the context key for the authenticated identity
+type identityKey struct{}
+
+// NewContext returns a copy of ctx carrying the identity
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity stored in ctx, or nil
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// HasScope reports whether the caller in ctx is granted scope
+func HasScope(ctx context.Context, scope string) bool {
+	return FromContext(ctx).Has(scope)
+}
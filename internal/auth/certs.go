@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+)
+
+// MethodClientCert is the Identity.Method for callers using a TLS client certificate
+const MethodClientCert = "client_cert"
+
+// certRule maps certificates matching all of its non-empty fields to an identity
+type certRule struct {
+	commonName string
+	dnsName    string
+	uri        string
+	email      string
+	identity   *Identity
+}
+
+// matches reports whether cert satisfies every criterion of the rule
+func (r certRule) matches(cert *x509.Certificate) bool {
+	if r.commonName != "" && cert.Subject.CommonName != r.commonName {
+		return false
+	}
+	if r.dnsName != "" && !slices.Contains(cert.DNSNames, r.dnsName) {
+		return false
+	}
+	if r.email != "" && !slices.Contains(cert.EmailAddresses, r.email) {
+		return false
+	}
+	if r.uri != "" {
+		found := slices.ContainsFunc(cert.URIs, func(u *url.URL) bool {
+			return u.String() == r.uri
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CertMapper maps verified client certificates onto identities. Like KeySet
+// it can be updated at runtime when the configuration is reloaded.
+type CertMapper struct {
+	mu    sync.RWMutex
+	rules []certRule
+}
+
+// NewCertMapper builds a mapper from the configured client certificate rules
+func NewCertMapper(certs []config.ClientCertConfig) (*CertMapper, error) {
+	m := &CertMapper{}
+	if err := m.Update(certs); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Update replaces the rules with those in certs
+func (m *CertMapper) Update(certs []config.ClientCertConfig) error {
+	apply, err := m.Prepare(certs)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare checks the rules in certs and returns a function that puts them in
+// place
+func (m *CertMapper) Prepare(certs []config.ClientCertConfig) (func(), error) {
+	rules := make([]certRule, 0, len(certs))
+	names := make(map[string]bool)
+	for _, c := range certs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("client cert entries need a name")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate client cert name %q", c.Name)
+		}
+		names[c.Name] = true
+
+		if c.CommonName == "" && c.DNSName == "" && c.URI == "" && c.Email == "" {
+			return nil, fmt.Errorf("client cert %q: needs at least one of common_name, dns_name, uri or email", c.Name)
+		}
+		scopes, err := ResolveScopes(c.Roles, c.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("client cert %q: %w", c.Name, err)
+		}
+		rules = append(rules, certRule{
+			commonName: c.CommonName,
+			dnsName:    c.DNSName,
+			uri:        c.URI,
+			email:      c.Email,
+			identity:   &Identity{Name: c.Name, Method: MethodClientCert, Scopes: scopes},
+		})
+	}
+
+	return func() {
+		m.mu.Lock()
+		m.rules = rules
+		m.mu.Unlock()
+	}, nil
+}
+
+// Identify returns the identity of the first rule matching cert. The
+// certificate must already have been verified against the client CA.
+func (m *CertMapper) Identify(cert *x509.Certificate) (*Identity, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, rule := range m.rules {
+		if rule.matches(cert) {
+			return rule.identity, true
+		}
+	}
+	return nil, false
+}
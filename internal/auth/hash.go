@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// hashScheme prefixes stored hashes so the format can change later
+	hashScheme = "sha256"
+
+	// saltLength and keyLength are in bytes
+	saltLength = 16
+	keyLength  = 32
+)
+
+// keyHash is a parsed salted key hash
+type keyHash struct {
+	salt   []byte
+	digest []byte
+}
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	buf := make([]byte, keyLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashKey returns a salted hash of key in the form sha256:<salt>:<digest>,
+// suitable for security.api_keys[].key_hash and the key store
+func HashKey(key string) (string, error) {
+	h, err := newKeyHash(key)
+	if err != nil {
+		return "", err
+	}
+	return h.String(), nil
+}
+
+func newKeyHash(key string) (keyHash, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return keyHash{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return keyHash{salt: salt, digest: digest(salt, key)}, nil
+}
+
+// parseKeyHash parses a hash produced by HashKey
+func parseKeyHash(s string) (keyHash, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] != hashScheme {
+		return keyHash{}, fmt.Errorf("key hash must have the form %s:<salt>:<digest>", hashScheme)
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil || len(salt) == 0 {
+		return keyHash{}, fmt.Errorf("invalid key hash salt")
+	}
+	sum, err := hex.DecodeString(parts[2])
+	if err != nil || len(sum) != sha256.Size {
+		return keyHash{}, fmt.Errorf("invalid key hash digest")
+	}
+	return keyHash{salt: salt, digest: sum}, nil
+}
+
+// String formats the hash for storage
+func (h keyHash) String() string {
+	return hashScheme + ":" + hex.EncodeToString(h.salt) + ":" + hex.EncodeToString(h.digest)
+}
+
+// matches reports in constant time whether presented hashes to h
+func (h keyHash) matches(presented string) bool {
+	return subtle.ConstantTimeCompare(digest(h.salt, presented), h.digest) == 1
+}
+
+func digest(salt []byte, key string) []byte {
+	sum := sha256.New()
+	sum.Write(salt)
+	sum.Write([]byte(key))
+	return sum.Sum(nil)
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// IPPolicy restricts the source addresses a request may come from. A nil
+// policy allows every address.
+type IPPolicy struct {
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// NewIPPolicy builds a policy from allow and deny lists of CIDRs or single
+// addresses. Denied addresses are rejected even when also allowed, and an
+// empty allow list allows everything not denied. It returns nil if both
+// lists are empty.
+func NewIPPolicy(allow, deny []string) (*IPPolicy, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	allowed, err := ParsePrefixes(allow)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := ParsePrefixes(deny)
+	if err != nil {
+		return nil, err
+	}
+	return &IPPolicy{allow: allowed, deny: denied}, nil
+}
+
+// Allows reports whether requests from addr are permitted
+func (p *IPPolicy) Allows(addr netip.Addr) bool {
+	if p == nil {
+		return true
+	}
+	if !addr.IsValid() {
+		return false
+	}
+	if ContainsAddr(p.deny, addr) {
+		return false
+	}
+	return len(p.allow) == 0 || ContainsAddr(p.allow, addr)
+}
+
+// ParsePrefixes parses CIDRs, accepting single addresses as /32 or /128
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", s)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ContainsAddr reports whether any of prefixes contains addr
+func ContainsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
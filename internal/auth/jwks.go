@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksMinRefresh limits how often an unknown key ID triggers a reload,
+	// so tokens with made-up key IDs cannot hammer the IdP
+	jwksMinRefresh = time.Minute
+
+	// jwksMaxSize bounds the size of a fetched key set
+	jwksMaxSize = 1 << 20
+
+	// jwksFetchTimeout bounds a single fetch of a remote key set
+	jwksFetchTimeout = 10 * time.Second
+)
+
+// errUnknownKey is returned when no key matches the token's key ID
+var errUnknownKey = errors.New("no matching key in JWKS")
+
+// jwk is a single JSON Web Key. Only the fields for signature keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwks holds the verification keys loaded from a JWKS file or URL. Keys are
+// reloaded after the refresh interval, or earlier when a token names a key
+// ID that is not known yet, e.g. after the IdP rotated its keys.
+type jwks struct {
+	file    string
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu   sync.Mutex
+	keys map[string]crypto.PublicKey
+	// loaded is the time of the last load attempt, successful or not
+	loaded time.Time
+	// loading is set while a reload runs, so only one runs at a time
+	loading bool
+}
+
+func newJWKS(ctx context.Context, file, url string, refresh time.Duration) (*jwks, error) {
+	s := &jwks{
+		file:    file,
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+	}
+	keys, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	s.loaded = time.Now()
+	return s, nil
+}
+
+// key returns the key for kid. An empty kid is accepted when the set holds
+// exactly one key.
+func (s *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	age := time.Since(s.loaded)
+	_, known := s.lookup(kid)
+	reload := !s.loading && (age > s.refresh || (!known && age > jwksMinRefresh))
+	if reload {
+		s.loading = true
+		s.loaded = time.Now()
+	}
+	s.mu.Unlock()
+
+	if reload {
+		s.reload(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.lookup(kid)
+	if !ok {
+		return nil, errUnknownKey
+	}
+	return key, nil
+}
+
+// reload reads the key set without holding s.mu and swaps it in. The fetch is
+// not cancelled with the request that triggered it, so the result still
+// serves the next requests. A failed reload keeps the previous keys so an
+// IdP outage does not lock everyone out.
+func (s *jwks) reload(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jwksFetchTimeout)
+	defer cancel()
+
+	keys, err := s.read(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.keys = keys
+	}
+	s.loading = false
+}
+
+func (s *jwks) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// read loads and parses the key set from the file or URL
+func (s *jwks) read(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var data []byte
+	var err error
+	if s.file != "" {
+		data, err = os.ReadFile(s.file)
+	} else {
+		data, err = s.fetch(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS: %w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *jwks) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, jwksMaxSize))
+}
+
+// parseJWKS parses a JWKS document into public keys by key ID. Keys that are
+// not meant for signatures or use an unsupported type are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signature keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, returning nil for unsupported key types
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MethodJWT is the Identity.Method for callers using a bearer token
+const MethodJWT = "jwt"
+
+// Defaults for unset JWT settings
+const (
+	defaultJWKSRefresh = time.Hour
+	defaultClockSkew   = 30 * time.Second
+	defaultNameClaim   = "sub"
+	defaultScopeClaim  = "scope"
+	defaultGroupsClaim = "groups"
+)
+
+// defaultAlgorithms are the asymmetric algorithms accepted unless configured.
+// Symmetric algorithms are never accepted since the keys come from a JWKS.
+var defaultAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// JWTVerifier authenticates bearer tokens issued by an external IdP and maps
+// their claims onto scopes. The settings can be replaced at runtime when the
+// configuration is reloaded.
+type JWTVerifier struct {
+	mu       sync.RWMutex
+	settings *jwtSettings
+}
+
+// jwtSettings is everything a token is checked against
+type jwtSettings struct {
+	keys        *jwks
+	parser      *jwt.Parser
+	nameClaim   string
+	scopeClaim  string
+	groupsClaim string
+	groups      map[string]Scopes
+}
+
+// NewJWTVerifier loads the JWKS and prepares token validation. Issuer and
+// audience are required so tokens meant for other services are rejected.
+func NewJWTVerifier(ctx context.Context, cfg config.JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{}
+	if err := v.Update(ctx, cfg); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Update replaces the JWKS, issuer, audience and claim mappings with those in
+// cfg. The key set is loaded again before the settings are swapped, so on
+// error the verifier keeps the previous settings.
+func (v *JWTVerifier) Update(ctx context.Context, cfg config.JWTConfig) error {
+	apply, err := v.Prepare(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare loads the key set and settings in cfg and returns a function that
+// puts them in place
+func (v *JWTVerifier) Prepare(ctx context.Context, cfg config.JWTConfig) (func(), error) {
+	settings, err := newJWTSettings(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		v.mu.Lock()
+		v.settings = settings
+		v.mu.Unlock()
+	}, nil
+}
+
+func newJWTSettings(ctx context.Context, cfg config.JWTConfig) (*jwtSettings, error) {
+	if (cfg.JWKSFile == "") == (cfg.JWKSURL == "") {
+		return nil, errors.New("jwt needs exactly one of jwks_file or jwks_url")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt needs an issuer and an audience")
+	}
+
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+	for _, alg := range algorithms {
+		if strings.HasPrefix(alg, "HS") || alg == "none" {
+			return nil, fmt.Errorf("jwt algorithm %q is not allowed", alg)
+		}
+	}
+	refresh := cfg.JWKSRefresh
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+	skew := cfg.ClockSkew
+	if skew <= 0 {
+		skew = defaultClockSkew
+	}
+
+	groups := make(map[string]Scopes, len(cfg.GroupRoles))
+	for _, g := range cfg.GroupRoles {
+		scopes, err := ResolveScopes(g.Roles, g.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt group %q: %w", g.Group, err)
+		}
+		groups[g.Group] = scopes
+	}
+
+	keys, err := newJWKS(ctx, cfg.JWKSFile, cfg.JWKSURL, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtSettings{
+		keys: keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(algorithms),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience),
+			jwt.WithLeeway(skew),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+		nameClaim:   orDefault(cfg.NameClaim, defaultNameClaim),
+		scopeClaim:  orDefault(cfg.ScopeClaim, defaultScopeClaim),
+		groupsClaim: orDefault(cfg.GroupsClaim, defaultGroupsClaim),
+		groups:      groups,
+	}, nil
+}
+
+// Verify checks the token's signature, issuer, audience and lifetime and
+// returns the identity it describes. Scopes come from the scope claim,
+// limited to the scopes this API knows, and from the configured group roles.
+// The "*" scope can only be granted through a group.
+func (v *JWTVerifier) Verify(ctx context.Context, raw string) (*Identity, error) {
+	v.mu.RLock()
+	s := v.settings
+	v.mu.RUnlock()
+
+	claims := jwt.MapClaims{}
+	_, err := s.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := claimValue(claims, s.nameClaim).(string)
+	if name == "" {
+		return nil, fmt.Errorf("token has no %s claim", s.nameClaim)
+	}
+
+	scopes := make(Scopes)
+	for _, scope := range claimStrings(claimValue(claims, s.scopeClaim)) {
+		if knownScopes[scope] && scope != ScopeAll {
+			scopes[scope] = true
+		}
+	}
+	for _, group := range claimStrings(claimValue(claims, s.groupsClaim)) {
+		for scope := range s.groups[group] {
+			scopes[scope] = true
+		}
+	}
+
+	return &Identity{Name: name, Method: MethodJWT, Scopes: scopes}, nil
+}
+
+// claimValue returns a claim by name. Dots address nested objects, e.g.
+// "realm_access.roles".
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	var value interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[part]
+	}
+	return value
+}
+
+// claimStrings reads a claim that is either a space separated string, as
+// used for "scope", or an array of strings
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
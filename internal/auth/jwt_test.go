@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "unbound-api"
+	testKeyID    = "k1"
+)
+
+// newTestJWTVerifier writes a JWKS file holding the public half of a fresh
+// P-256 key and returns a verifier for it along with the private key
+func newTestJWTVerifier(t *testing.T) (*JWTVerifier, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	coord := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	pub, _ := priv.PublicKey.ECDH()
+	raw := pub.Bytes() // 0x04 || X || Y
+	doc, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "EC", "kid": testKeyID, "use": "sig", "crv": "P-256",
+			"x": coord(raw[1:33]), "y": coord(raw[33:]),
+		}},
+	})
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(file, doc, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	v, err := NewJWTVerifier(context.Background(), config.JWTConfig{
+		JWKSFile:  file,
+		Issuer:    testIssuer,
+		Audience:  testAudience,
+		ClockSkew: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	return v, priv
+}
+
+// validClaims returns claims that pass verification
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "alice",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "status:read stats:read *",
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	v, priv := newTestJWTVerifier(t)
+
+	sign := func(claims jwt.MapClaims, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+		token.Header["kid"] = kid
+		raw, err := token.SignedString(priv)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return raw
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	hs256 := func() string {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims()).SignedString([]byte("shared secret"))
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return raw
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", sign(validClaims(), testKeyID), true},
+		{"bad issuer", sign(with("iss", "https://evil.example.com"), testKeyID), false},
+		{"bad audience", sign(with("aud", "other-service"), testKeyID), false},
+		{"expired", sign(with("exp", time.Now().Add(-time.Minute).Unix()), testKeyID), false},
+		{"expired within clock skew", sign(with("exp", time.Now().Add(-10*time.Second).Unix()), testKeyID), true},
+		{"issued in the future beyond clock skew", sign(with("iat", time.Now().Add(time.Minute).Unix()), testKeyID), false},
+		{"no expiry", sign(with("exp", nil), testKeyID), false},
+		{"unknown kid", sign(validClaims(), "k2"), false},
+		{"HS256", hs256(), false},
+		{"no subject", sign(with("sub", nil), testKeyID), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := v.Verify(context.Background(), tt.token)
+			if tt.ok != (err == nil) {
+				t.Fatalf("Verify error = %v, want ok %v", err, tt.ok)
+			}
+			if !tt.ok {
+				return
+			}
+			if identity.Name != "alice" || identity.Method != MethodJWT {
+				t.Errorf("identity = %s/%s, want alice/%s", identity.Name, identity.Method, MethodJWT)
+			}
+			if !identity.Has(ScopeStatusRead) || identity.Scopes[ScopeAll] {
+				t.Errorf("scopes = %v, want status:read without *", identity.Scopes.List())
+			}
+		})
+	}
+}
+
+func TestNewJWTVerifierRejectsSymmetricAlgorithms(t *testing.T) {
+	_, err := NewJWTVerifier(context.Background(), config.JWTConfig{
+		JWKSFile:   "unused.json",
+		Issuer:     testIssuer,
+		Audience:   testAudience,
+		Algorithms: []string{"HS256"},
+	})
+	if err == nil {
+		t.Fatal("NewJWTVerifier accepted HS256")
+	}
+}
+
+func TestJWTUpdate(t *testing.T) {
+	v, priv := newTestJWTVerifier(t)
+	raw, err := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims()).SignedString(priv)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	file := v.settings.keys.file
+
+	// A broken configuration keeps the previous settings
+	if err := v.Update(context.Background(), config.JWTConfig{JWKSFile: file}); err == nil {
+		t.Fatal("Update accepted a configuration without issuer and audience")
+	}
+	if _, err := v.Verify(context.Background(), raw); err != nil {
+		t.Fatalf("Verify after a failed Update: %v", err)
+	}
+
+	if err := v.Update(context.Background(), config.JWTConfig{
+		JWKSFile: file,
+		Issuer:   testIssuer,
+		Audience: "other-service",
+	}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), raw); err == nil {
+		t.Fatal("Verify accepted a token for the previous audience")
+	}
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+)
+
+// MethodAPIKey is the Identity.Method for callers using X-API-Key
+const MethodAPIKey = "api_key"
+
+// apiKey is a configured key and the identity it authenticates. Plaintext
+// keys from the configuration are hashed on load so they are not kept around.
+type apiKey struct {
+	hash     keyHash
+	identity *Identity
+}
+
+// KeySet holds the configured API keys. It can be replaced at runtime so a
+// configuration reload takes effect without rebuilding the router.
+type KeySet struct {
+	mu    sync.RWMutex
+	keys  []apiKey
+	store *Store
+}
+
+// NewKeySet builds a key set from the security configuration, opening the
+// key store if one is configured
+func NewKeySet(cfg config.SecurityConfig) (*KeySet, error) {
+	ks := &KeySet{}
+	if err := ks.Update(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.KeyStore != "" {
+		store, err := OpenStore(cfg.KeyStore)
+		if err != nil {
+			return nil, err
+		}
+		ks.store = store
+	}
+	return ks, nil
+}
+
+// Store returns the managed key store, or nil if none is configured
+func (ks *KeySet) Store() *Store {
+	return ks.store
+}
+
+// legacyExcluded are the scopes not granted to the legacy api_key, which
+// keeps the access it always had
+var legacyExcluded = map[string]bool{
+	ScopeAll:            true,
+	ScopeCookieSecrets:  true,
+	ScopeKeysManage:     true,
+	ScopeAuditRead:      true,
+	ScopeChangesApprove: true,
+}
+
+// Update replaces the keys with those in cfg. The legacy api_key gets every
+// scope except those in legacyExcluded, and the legacy admin_api_key is
+// granted the admin role. The key store is not reopened.
+func (ks *KeySet) Update(cfg config.SecurityConfig) error {
+	apply, err := ks.Prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare checks the keys in cfg and returns a function that puts them in
+// place, so a reload can check every setting before changing any
+func (ks *KeySet) Prepare(cfg config.SecurityConfig) (func(), error) {
+	var keys []apiKey
+
+	if cfg.APIKey != "" {
+		scopes := make(Scopes)
+		for scope := range knownScopes {
+			if !legacyExcluded[scope] {
+				scopes[scope] = true
+			}
+		}
+		hash, err := newKeyHash(cfg.APIKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, apiKey{
+			hash:     hash,
+			identity: &Identity{Name: "default", Method: MethodAPIKey, Scopes: scopes},
+		})
+	}
+	if cfg.AdminAPIKey != "" {
+		hash, err := newKeyHash(cfg.AdminAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, apiKey{
+			hash:     hash,
+			identity: &Identity{Name: "admin", Method: MethodAPIKey, Scopes: Scopes{ScopeAll: true}},
+		})
+	}
+
+	names := make(map[string]bool)
+	for _, k := range cfg.APIKeys {
+		if k.Name == "" || (k.Key == "") == (k.KeyHash == "") {
+			return nil, fmt.Errorf("api key entries need a name and either a key or a key_hash")
+		}
+		if names[k.Name] {
+			return nil, fmt.Errorf("duplicate api key name %q", k.Name)
+		}
+		names[k.Name] = true
+
+		scopes, err := ResolveScopes(k.Roles, k.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		var hash keyHash
+		if k.KeyHash != "" {
+			hash, err = parseKeyHash(k.KeyHash)
+		} else {
+			hash, err = newKeyHash(k.Key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		sources, err := NewIPPolicy(k.AllowCIDRs, k.DenyCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("api key %q: %w", k.Name, err)
+		}
+		keys = append(keys, apiKey{
+			hash:     hash,
+			identity: &Identity{Name: k.Name, Method: MethodAPIKey, Scopes: scopes, Sources: sources},
+		})
+	}
+
+	return func() {
+		ks.mu.Lock()
+		ks.keys = keys
+		ks.mu.Unlock()
+	}, nil
+}
+
+// Lookup returns the identity for a presented key, checking the configured
+// keys before the key store. Every key is compared in constant time so the
+// response time does not reveal which key nearly matched.
+func (ks *KeySet) Lookup(presented string) (*Identity, bool) {
+	ks.mu.RLock()
+	var found *Identity
+	for _, k := range ks.keys {
+		if k.hash.matches(presented) && found == nil {
+			found = k.identity
+		}
+	}
+	ks.mu.RUnlock()
+
+	if ks.store != nil {
+		if stored := ks.store.lookup(presented); found == nil {
+			found = stored
+		}
+	}
+	return found, found != nil
+}
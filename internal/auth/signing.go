@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/pkg/signing"
+)
+
+// MethodSignature is the Identity.Method for callers signing their requests
+const MethodSignature = "signature"
+
+const (
+	// defaultSigningWindow is how far a signed timestamp may be from now
+	defaultSigningWindow = 5 * time.Minute
+
+	// minSigningSecret is the shortest secret accepted for a signing key
+	minSigningSecret = 32
+
+	// maxSignedBody bounds the request body read to check its hash
+	maxSignedBody = 1 << 20
+
+	// maxNonces bounds the nonces remembered within one window
+	maxNonces = 100000
+
+	// nonceSweepInterval is how often expired nonces are dropped
+	nonceSweepInterval = time.Minute
+)
+
+// signingKey is a shared secret and the identity it authenticates
+type signingKey struct {
+	secret   []byte
+	identity *Identity
+}
+
+// SignatureVerifier authenticates requests signed with a shared secret. Each
+// nonce is accepted once per key within the window, so a captured request
+// cannot be replayed. Keys can be replaced at runtime when the configuration
+// is reloaded.
+type SignatureVerifier struct {
+	mu     sync.RWMutex
+	keys   map[string]signingKey
+	window time.Duration
+
+	nonceMu   sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewSignatureVerifier builds a verifier from the signing configuration
+func NewSignatureVerifier(cfg config.SigningConfig) (*SignatureVerifier, error) {
+	v := &SignatureVerifier{nonces: make(map[string]time.Time)}
+	if err := v.Update(cfg); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Update replaces the signing keys and window with those in cfg. Nonces
+// already seen are kept.
+func (v *SignatureVerifier) Update(cfg config.SigningConfig) error {
+	apply, err := v.Prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare checks the signing keys in cfg and returns a function that puts
+// them in place
+func (v *SignatureVerifier) Prepare(cfg config.SigningConfig) (func(), error) {
+	keys := make(map[string]signingKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		if k.ID == "" {
+			return nil, errors.New("signing keys need an id")
+		}
+		if _, ok := keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate signing key id %q", k.ID)
+		}
+		if len(k.Secret) < minSigningSecret {
+			return nil, fmt.Errorf("signing key %q: secret must be at least %d characters", k.ID, minSigningSecret)
+		}
+		scopes, err := ResolveScopes(k.Roles, k.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", k.ID, err)
+		}
+		sources, err := NewIPPolicy(k.AllowCIDRs, k.DenyCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", k.ID, err)
+		}
+		keys[k.ID] = signingKey{
+			secret:   []byte(k.Secret),
+			identity: &Identity{Name: k.ID, Method: MethodSignature, Scopes: scopes, Sources: sources},
+		}
+	}
+
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultSigningWindow
+	}
+
+	return func() {
+		v.mu.Lock()
+		v.keys = keys
+		v.window = window
+		v.mu.Unlock()
+	}, nil
+}
+
+// Verify checks the signature headers of r and returns the identity of the
+// signing key. The body is read to check its hash and then restored.
+func (v *SignatureVerifier) Verify(r *http.Request) (*Identity, error) {
+	keyID := r.Header.Get(signing.HeaderKeyID)
+	timestamp := r.Header.Get(signing.HeaderTimestamp)
+	nonce := r.Header.Get(signing.HeaderNonce)
+	signature := r.Header.Get(signing.HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return nil, errors.New("incomplete signature headers")
+	}
+	if len(nonce) < 16 || len(nonce) > 128 {
+		return nil, errors.New("nonce must be 16 to 128 characters")
+	}
+
+	v.mu.RLock()
+	key, ok := v.keys[keyID]
+	window := v.window
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid signature timestamp")
+	}
+	signedAt := time.Unix(seconds, 0)
+	now := time.Now()
+	if signedAt.Before(now.Add(-window)) || signedAt.After(now.Add(window)) {
+		return nil, errors.New("signature timestamp outside the allowed window")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, maxSignedBody+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(body) > maxSignedBody {
+			return nil, fmt.Errorf("signed request body exceeds %d bytes", maxSignedBody)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	expected := signing.Compute(key.secret, signing.StringToSign(r.Method, r.URL.RequestURI(), timestamp, nonce, body))
+	if !signing.Equal(expected, signature) {
+		return nil, errors.New("signature does not match")
+	}
+
+	// Only remember nonces of valid signatures, so unauthenticated callers
+	// cannot fill the cache
+	if err := v.useNonce(keyID+"\x00"+nonce, signedAt.Add(window), now); err != nil {
+		return nil, err
+	}
+	return key.identity, nil
+}
+
+// useNonce records a nonce until expires, failing if it was already used
+func (v *SignatureVerifier) useNonce(nonce string, expires, now time.Time) error {
+	v.nonceMu.Lock()
+	defer v.nonceMu.Unlock()
+
+	if now.Sub(v.lastSweep) >= nonceSweepInterval || len(v.nonces) >= maxNonces {
+		for n, exp := range v.nonces {
+			if now.After(exp) {
+				delete(v.nonces, n)
+			}
+		}
+		v.lastSweep = now
+	}
+
+	if exp, ok := v.nonces[nonce]; ok && !now.After(exp) {
+		return errors.New("nonce has already been used")
+	}
+	if len(v.nonces) >= maxNonces {
+		return errors.New("too many signed requests within the window")
+	}
+	v.nonces[nonce] = expires
+	return nil
+}
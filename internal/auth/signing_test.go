@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/pkg/signing"
+)
+
+const (
+	testSigningKeyID  = "ci"
+	testSigningSecret = "0123456789abcdef0123456789abcdef"
+)
+
+func newTestSignatureVerifier(t *testing.T) *SignatureVerifier {
+	t.Helper()
+
+	v, err := NewSignatureVerifier(config.SigningConfig{
+		Window: time.Minute,
+		Keys: []config.SigningKeyConfig{{
+			ID:     testSigningKeyID,
+			Secret: testSigningSecret,
+			Roles:  []string{"operator"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewSignatureVerifier: %v", err)
+	}
+	return v
+}
+
+// signedRequest returns a request signed with the test key
+func signedRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if err := signing.Sign(r, testSigningKeyID, []byte(testSigningSecret)); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return r
+}
+
+func TestSignatureVerify(t *testing.T) {
+	v := newTestSignatureVerifier(t)
+
+	r := signedRequest(t, http.MethodPost, "/api/v1/flush?domain=example.com", `{"a":1}`)
+	identity, err := v.Verify(r)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if identity.Name != testSigningKeyID || identity.Method != MethodSignature || !identity.Has(ScopeCacheFlush) {
+		t.Errorf("identity = %+v, want %s with cache:flush", identity, testSigningKeyID)
+	}
+	if body, _ := io.ReadAll(r.Body); string(body) != `{"a":1}` {
+		t.Errorf("body after Verify = %q, want it restored", body)
+	}
+}
+
+func TestSignatureVerifyRejects(t *testing.T) {
+	v := newTestSignatureVerifier(t)
+
+	tests := []struct {
+		name   string
+		modify func(r *http.Request) *http.Request
+	}{
+		{"body changed after signing", func(r *http.Request) *http.Request {
+			r.Body = io.NopCloser(strings.NewReader(`{"a":2}`))
+			return r
+		}},
+		{"query changed after signing", func(r *http.Request) *http.Request {
+			r.URL.RawQuery = "domain=."
+			return r
+		}},
+		{"method changed after signing", func(r *http.Request) *http.Request {
+			r.Method = http.MethodDelete
+			return r
+		}},
+		{"timestamp outside the window", func(r *http.Request) *http.Request {
+			r.Header.Set(signing.HeaderTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+			return r
+		}},
+		{"unknown key", func(r *http.Request) *http.Request {
+			r.Header.Set(signing.HeaderKeyID, "other")
+			return r
+		}},
+		{"missing signature", func(r *http.Request) *http.Request {
+			r.Header.Del(signing.HeaderSignature)
+			return r
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.modify(signedRequest(t, http.MethodPost, "/api/v1/flush?domain=example.com", `{"a":1}`))
+			if _, err := v.Verify(r); err == nil {
+				t.Fatal("Verify accepted the request")
+			}
+		})
+	}
+}
+
+func TestSignatureVerifyRejectsReplay(t *testing.T) {
+	v := newTestSignatureVerifier(t)
+
+	r := signedRequest(t, http.MethodPost, "/api/v1/reload", "")
+	replay := r.Clone(r.Context())
+	replay.Body = http.NoBody
+
+	if _, err := v.Verify(r); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := v.Verify(replay); err == nil {
+		t.Fatal("Verify accepted a replayed nonce")
+	}
+}
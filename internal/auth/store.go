@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound is returned for an unknown key ID
+	ErrKeyNotFound = errors.New("api key not found")
+	// ErrKeyExists is returned when an active key already uses the name
+	ErrKeyExists = errors.New("api key name already in use")
+	// ErrKeyRevoked is returned when changing a revoked key
+	ErrKeyRevoked = errors.New("api key is revoked")
+	// ErrKeyExpired is returned when changing the expiry of an expired key
+	ErrKeyExpired = errors.New("api key has expired")
+)
+
+// Key states reported by StoredKey.State
+const (
+	KeyActive  = "active"
+	KeyExpired = "expired"
+	KeyRevoked = "revoked"
+)
+
+// StoredKey is a managed API key. Only salted hashes of the secret are kept.
+type StoredKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Roles  []string `json:"roles,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// AllowCIDRs and DenyCIDRs restrict where the key may be used from
+	AllowCIDRs []string   `json:"allow_cidrs,omitempty"`
+	DenyCIDRs  []string   `json:"deny_cidrs,omitempty"`
+	Hash       string     `json:"hash"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// PreviousHash is the secret replaced by the last rotation, which stays
+	// valid until PreviousExpiresAt so clients can switch over
+	PreviousHash      string     `json:"previous_hash,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+}
+
+// State reports whether the key is active, expired or revoked at now
+func (k *StoredKey) State(now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return KeyRevoked
+	case k.ExpiresAt != nil && !now.Before(*k.ExpiresAt):
+		return KeyExpired
+	default:
+		return KeyActive
+	}
+}
+
+// storedKey is a StoredKey with its hashes parsed for lookups
+type storedKey struct {
+	StoredKey
+	hash     keyHash
+	previous *keyHash
+	identity *Identity
+}
+
+// Store persists managed API keys to a JSON file. Changes are written before
+// they are applied, so a key is never usable unless it has been saved.
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	keys []*storedKey
+}
+
+// OpenStore loads the key store at path, starting empty if it does not exist
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key store: %w", err)
+	}
+
+	var records []StoredKey
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse key store: %w", err)
+	}
+	for _, record := range records {
+		k, err := loadKey(record)
+		if err != nil {
+			return nil, fmt.Errorf("key store entry %q: %w", record.ID, err)
+		}
+		s.keys = append(s.keys, k)
+	}
+	return s, nil
+}
+
+func loadKey(record StoredKey) (*storedKey, error) {
+	hash, err := parseKeyHash(record.Hash)
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := ResolveScopes(record.Roles, record.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := NewIPPolicy(record.AllowCIDRs, record.DenyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &storedKey{
+		StoredKey: record,
+		hash:      hash,
+		identity:  &Identity{Name: record.Name, Method: MethodAPIKey, Scopes: scopes, Sources: sources},
+	}
+	if record.PreviousHash != "" {
+		previous, err := parseKeyHash(record.PreviousHash)
+		if err != nil {
+			return nil, err
+		}
+		k.previous = &previous
+	}
+	return k, nil
+}
+
+// List returns every key, including expired and revoked ones
+func (s *Store) List() []StoredKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]StoredKey, len(s.keys))
+	for i, k := range s.keys {
+		list[i] = k.StoredKey
+	}
+	return list
+}
+
+// Get returns the key with the given ID
+func (s *Store) Get(id string) (StoredKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.ID == id {
+			return k.StoredKey, true
+		}
+	}
+	return StoredKey{}, false
+}
+
+// NewKey describes a key to create
+type NewKey struct {
+	Name       string
+	Roles      []string
+	Scopes     []string
+	AllowCIDRs []string
+	DenyCIDRs  []string
+	ExpiresAt  *time.Time
+}
+
+// Create adds a key and returns it together with its secret, which is not
+// stored and cannot be retrieved again
+func (s *Store) Create(spec NewKey) (StoredKey, string, error) {
+	id, err := newKeyID()
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+	secret, err := GenerateKey()
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+	hash, err := newKeyHash(secret)
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, k := range s.keys {
+		if k.Name == spec.Name && k.State(now) == KeyActive {
+			return StoredKey{}, "", ErrKeyExists
+		}
+	}
+
+	k, err := loadKey(StoredKey{
+		ID:         id,
+		Name:       spec.Name,
+		Roles:      spec.Roles,
+		Scopes:     spec.Scopes,
+		AllowCIDRs: spec.AllowCIDRs,
+		DenyCIDRs:  spec.DenyCIDRs,
+		Hash:       hash.String(),
+		CreatedAt:  now,
+		ExpiresAt:  spec.ExpiresAt,
+	})
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+	keys := append(s.keys[:len(s.keys):len(s.keys)], k)
+	if err := s.save(keys); err != nil {
+		return StoredKey{}, "", err
+	}
+	s.keys = keys
+	return k.StoredKey, secret, nil
+}
+
+// Rotate replaces the secret of a key and returns the new one. The old
+// secret keeps working for grace, or stops immediately if grace is zero.
+func (s *Store) Rotate(id string, grace time.Duration) (StoredKey, string, error) {
+	secret, err := GenerateKey()
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+	hash, err := newKeyHash(secret)
+	if err != nil {
+		return StoredKey{}, "", err
+	}
+
+	updated, err := s.update(id, func(k *StoredKey, now time.Time) error {
+		k.PreviousHash, k.PreviousExpiresAt = "", nil
+		if grace > 0 {
+			until := now.Add(grace)
+			k.PreviousHash, k.PreviousExpiresAt = k.Hash, &until
+		}
+		k.Hash = hash.String()
+		k.RotatedAt = &now
+		return nil
+	})
+	return updated, secret, err
+}
+
+// Expire sets the time after which a key stops working. A key that has
+// already expired stays expired; create a new one instead.
+func (s *Store) Expire(id string, at time.Time) (StoredKey, error) {
+	at = at.UTC()
+	return s.update(id, func(k *StoredKey, now time.Time) error {
+		if k.State(now) == KeyExpired {
+			return ErrKeyExpired
+		}
+		k.ExpiresAt = &at
+		return nil
+	})
+}
+
+// Revoke disables a key permanently. The record is kept for auditing.
+func (s *Store) Revoke(id string) (StoredKey, error) {
+	return s.update(id, func(k *StoredKey, now time.Time) error {
+		k.RevokedAt = &now
+		k.PreviousHash, k.PreviousExpiresAt = "", nil
+		return nil
+	})
+}
+
+// update applies change to a copy of the key, saves it and swaps it in. An
+// error from change leaves the key as it was.
+func (s *Store) update(id string, change func(k *StoredKey, now time.Time) error) (StoredKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, k := range s.keys {
+		if k.ID != id {
+			continue
+		}
+		if k.RevokedAt != nil {
+			return StoredKey{}, ErrKeyRevoked
+		}
+
+		record := k.StoredKey
+		if err := change(&record, time.Now().UTC()); err != nil {
+			return StoredKey{}, err
+		}
+		updated, err := loadKey(record)
+		if err != nil {
+			return StoredKey{}, err
+		}
+
+		keys := append([]*storedKey(nil), s.keys...)
+		keys[i] = updated
+		if err := s.save(keys); err != nil {
+			return StoredKey{}, err
+		}
+		s.keys = keys
+		return updated.StoredKey, nil
+	}
+	return StoredKey{}, ErrKeyNotFound
+}
+
+// save atomically writes keys to the store file
+func (s *Store) save(keys []*storedKey) error {
+	records := make([]StoredKey, len(keys))
+	for i, k := range keys {
+		records[i] = k.StoredKey
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode key store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".keys-*")
+	if err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the identity of the active key matching presented. Every
+// key is checked so the time taken does not depend on which one matched.
+func (s *Store) lookup(presented string) *Identity {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	var found *Identity
+	for _, k := range s.keys {
+		active := k.State(now) == KeyActive
+		match := k.hash.matches(presented)
+		if k.previous != nil && now.Before(*k.PreviousExpiresAt) && k.previous.matches(presented) {
+			match = true
+		}
+		if match && active && found == nil {
+			found = k.identity
+		}
+	}
+	return found
+}
+
+// newKeyID returns a random identifier for a managed key
+func newKeyID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
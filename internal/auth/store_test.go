@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	return s, path
+}
+
+// createTestKey adds a viewer key called name and returns it with its secret
+func createTestKey(t *testing.T, s *Store, name string) (StoredKey, string) {
+	t.Helper()
+
+	key, secret, err := s.Create(NewKey{Name: name, Roles: []string{"viewer"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return key, secret
+}
+
+func TestStoreCreate(t *testing.T) {
+	s, _ := newTestStore(t)
+	key, secret := createTestKey(t, s, "ci")
+
+	identity := s.lookup(secret)
+	if identity == nil || identity.Name != "ci" || !identity.Has(ScopeStatusRead) {
+		t.Fatalf("lookup = %+v, want ci with status:read", identity)
+	}
+	if s.lookup(secret+"x") != nil {
+		t.Error("lookup accepted a wrong secret")
+	}
+	if key.State(time.Now()) != KeyActive {
+		t.Errorf("state = %s, want %s", key.State(time.Now()), KeyActive)
+	}
+	if _, _, err := s.Create(NewKey{Name: "ci", Roles: []string{"viewer"}}); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Create with an active name: error = %v, want %v", err, ErrKeyExists)
+	}
+}
+
+func TestStoreRotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		oldAccepted bool
+	}{
+		{"with grace", time.Hour, true},
+		{"without grace", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			key, old := createTestKey(t, s, "ci")
+
+			rotated, secret, err := s.Rotate(key.ID, tt.grace)
+			if err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+			if secret == old || rotated.RotatedAt == nil {
+				t.Fatalf("Rotate kept the secret or did not record the rotation: %+v", rotated)
+			}
+			if s.lookup(secret) == nil {
+				t.Error("new secret rejected")
+			}
+			if got := s.lookup(old) != nil; got != tt.oldAccepted {
+				t.Errorf("old secret accepted = %v, want %v", got, tt.oldAccepted)
+			}
+		})
+	}
+}
+
+func TestStoreExpire(t *testing.T) {
+	s, _ := newTestStore(t)
+	key, secret := createTestKey(t, s, "ci")
+
+	later := time.Now().Add(time.Hour)
+	if _, err := s.Expire(key.ID, later); err != nil {
+		t.Fatalf("Expire in the future: %v", err)
+	}
+	if s.lookup(secret) == nil {
+		t.Fatal("key rejected before its expiry")
+	}
+
+	expired, err := s.Expire(key.ID, time.Now())
+	if err != nil {
+		t.Fatalf("Expire now: %v", err)
+	}
+	if expired.State(time.Now()) != KeyExpired || s.lookup(secret) != nil {
+		t.Fatal("expired key still accepted")
+	}
+
+	// Expiry is final, setting a later time must not bring the key back
+	if _, err := s.Expire(key.ID, later); !errors.Is(err, ErrKeyExpired) {
+		t.Fatalf("Expire of an expired key: error = %v, want %v", err, ErrKeyExpired)
+	}
+	if s.lookup(secret) != nil {
+		t.Error("expired key accepted after a refused extension")
+	}
+
+	// The name is free again once the key has expired
+	createTestKey(t, s, "ci")
+}
+
+func TestStoreRevoke(t *testing.T) {
+	s, _ := newTestStore(t)
+	key, secret := createTestKey(t, s, "ci")
+	if _, _, err := s.Rotate(key.ID, time.Hour); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	revoked, err := s.Revoke(key.ID)
+	if err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if revoked.State(time.Now()) != KeyRevoked || revoked.PreviousHash != "" {
+		t.Errorf("revoked key = %+v, want revoked without a previous secret", revoked)
+	}
+	if s.lookup(secret) != nil {
+		t.Error("previous secret accepted after Revoke")
+	}
+
+	if _, _, err := s.Rotate(key.ID, 0); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("Rotate: error = %v, want %v", err, ErrKeyRevoked)
+	}
+	if _, err := s.Expire(key.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrKeyRevoked) {
+		t.Errorf("Expire: error = %v, want %v", err, ErrKeyRevoked)
+	}
+	if _, err := s.Revoke("unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Revoke of an unknown key: error = %v, want %v", err, ErrKeyNotFound)
+	}
+}
+
+func TestStorePersistence(t *testing.T) {
+	s, path := newTestStore(t)
+	rotated, _ := createTestKey(t, s, "rotated")
+	_, old, err := s.Rotate(rotated.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	_, current, err := s.Rotate(rotated.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	expired, expiredSecret := createTestKey(t, s, "expired")
+	if _, err := s.Expire(expired.ID, time.Now()); err != nil {
+		t.Fatalf("Expire: %v", err)
+	}
+	revoked, revokedSecret := createTestKey(t, s, "revoked")
+	if _, err := s.Revoke(revoked.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	reopened, err := OpenStore(path)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	want, got := s.List(), reopened.List()
+	if len(got) != len(want) {
+		t.Fatalf("reopened store has %d keys, want %d", len(got), len(want))
+	}
+	now := time.Now()
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Hash != want[i].Hash || got[i].State(now) != want[i].State(now) {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		ok     bool
+	}{
+		{"current secret", current, true},
+		{"previous secret within grace", old, true},
+		{"expired key", expiredSecret, false},
+		{"revoked key", revokedSecret, false},
+	}
+	for _, tt := range tests {
+		if ok := reopened.lookup(tt.secret) != nil; ok != tt.ok {
+			t.Errorf("%s: accepted = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Package changes holds change requests for operations that need a second
+// person. A gated call creates a pending request; it only runs once another
+// identity with approver rights approves it before it expires.
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+)
+
+// Operations that can require approval
+const (
+	OpReload          = "reload"
+	OpStop            = "stop"
+	OpFlushZoneRoot   = "flush_zone_root"
+	OpRemoveLocalZone = "remove_local_zone"
+	OpRemoveLocalData = "remove_local_data"
+)
+
+// knownOperations lists the operations approval can be required for
+var knownOperations = []string{OpReload, OpStop, OpFlushZoneRoot, OpRemoveLocalZone, OpRemoveLocalData}
+
+// States of a change request
+const (
+	StatePending  = "pending"
+	StateApproved = "approved"
+	StateExecuted = "executed"
+	StateFailed   = "failed"
+	StateRejected = "rejected"
+	StateExpired  = "expired"
+)
+
+const (
+	// DefaultTTL is how long a request stays pending when approvals.ttl is unset
+	DefaultTTL = time.Hour
+
+	// DefaultBulkThreshold is used when approvals.bulk_threshold is unset
+	DefaultBulkThreshold = 10
+
+	// retention is how long finished requests are kept for inspection
+	retention = 24 * time.Hour
+)
+
+var (
+	ErrNotFound     = errors.New("change request not found")
+	ErrNotPending   = errors.New("change request is not pending")
+	ErrSelfApproval = errors.New("change requests must be approved by a different identity")
+)
+
+// Request is a call waiting for, or decided by, an approver. Body and
+// ContentType are kept to replay the call and are not shown to callers.
+// RequestedVia is the requester's authentication method, as the same name
+// may belong to different identities under different methods.
+type Request struct {
+	ID           string
+	Operation    string
+	Method       string
+	Path         string
+	Query        string
+	Body         []byte
+	ContentType  string
+	RequestedBy  string
+	RequestedVia string
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+	State        string
+	DecidedBy    string
+	DecidedAt    *time.Time
+	Status       int
+	Response     []byte
+}
+
+// state returns the request's state, reporting pending requests past their
+// expiry as expired
+func (r *Request) state(now time.Time) string {
+	if r.State == StatePending && now.After(r.ExpiresAt) {
+		return StateExpired
+	}
+	return r.State
+}
+
+// snapshot returns a copy of r with its current state
+func (r *Request) snapshot(now time.Time) Request {
+	c := *r
+	c.State = r.state(now)
+	return c
+}
+
+// Manager holds change requests in memory. Pending requests do not survive a
+// restart.
+type Manager struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	bulk       int
+	operations map[string]bool
+	requests   map[string]*Request
+}
+
+// NewManager creates a manager requiring approval for the configured
+// operations, or for every known operation if none are listed
+func NewManager(cfg config.ApprovalsConfig) (*Manager, error) {
+	ops := cfg.Operations
+	if len(ops) == 0 {
+		ops = knownOperations
+	}
+	operations := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		if !slices.Contains(knownOperations, op) {
+			return nil, fmt.Errorf("unknown approval operation %q", op)
+		}
+		operations[op] = true
+	}
+
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	bulk := cfg.BulkThreshold
+	if bulk <= 0 {
+		bulk = DefaultBulkThreshold
+	}
+	return &Manager{ttl: ttl, bulk: bulk, operations: operations, requests: make(map[string]*Request)}, nil
+}
+
+// Required reports whether op needs approval
+func (m *Manager) Required(op string) bool {
+	return m.operations[op]
+}
+
+// Bulk reports whether a removal of n names is large enough to need approval
+func (m *Manager) Bulk(n int) bool {
+	return n > m.bulk
+}
+
+// Create stores req as a new pending request, assigning its ID, state and
+// times
+func (m *Manager) Create(req Request) (Request, error) {
+	id, err := newID()
+	if err != nil {
+		return Request{}, err
+	}
+	now := time.Now().UTC()
+	req.ID = id
+	req.State = StatePending
+	req.CreatedAt = now
+	req.ExpiresAt = now.Add(m.ttl)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	m.requests[id] = &req
+	return req, nil
+}
+
+// prune drops requests finished for longer than the retention period. An
+// approved request that never completed is dropped as well.
+func (m *Manager) prune(now time.Time) {
+	for id, req := range m.requests {
+		finished := req.ExpiresAt
+		if req.DecidedAt != nil {
+			finished = *req.DecidedAt
+		}
+		if req.state(now) != StatePending && now.Sub(finished) > retention {
+			delete(m.requests, id)
+		}
+	}
+}
+
+// List returns the requests in state, or all of them if state is empty,
+// newest first
+func (m *Manager) List(state string) []Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	list := make([]Request, 0, len(m.requests))
+	for _, req := range m.requests {
+		if snap := req.snapshot(now); state == "" || snap.State == state {
+			list = append(list, snap)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Get returns the request with id
+func (m *Manager) Get(id string) (Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[id]
+	if !ok {
+		return Request{}, ErrNotFound
+	}
+	return req.snapshot(time.Now()), nil
+}
+
+// Approve marks a pending request as approved by approver, authenticated
+// through method, who must not be the requester. The caller then runs the
+// call and reports the outcome with Complete.
+func (m *Manager) Approve(id, method, approver string) (Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, err := m.pending(id)
+	if err != nil {
+		return Request{}, err
+	}
+	if req.IsRequester(method, approver) {
+		return Request{}, ErrSelfApproval
+	}
+	now := time.Now().UTC()
+	req.State = StateApproved
+	req.DecidedBy = approver
+	req.DecidedAt = &now
+	return *req, nil
+}
+
+// IsRequester reports whether the identity name, authenticated through
+// method, created the request
+func (r *Request) IsRequester(method, name string) bool {
+	return r.RequestedVia == method && r.RequestedBy == name
+}
+
+// Reject marks a pending request as rejected by who
+func (m *Manager) Reject(id, who string) (Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, err := m.pending(id)
+	if err != nil {
+		return Request{}, err
+	}
+	now := time.Now().UTC()
+	req.State = StateRejected
+	req.DecidedBy = who
+	req.DecidedAt = &now
+	return *req, nil
+}
+
+// pending returns the request with id if it is still pending
+func (m *Manager) pending(id string) (*Request, error) {
+	req, ok := m.requests[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if req.state(time.Now()) != StatePending {
+		return nil, ErrNotPending
+	}
+	return req, nil
+}
+
+// Complete records the response of an approved call
+func (m *Manager) Complete(id string, status int, response []byte) Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req := m.requests[id]
+	req.Status = status
+	req.Response = response
+	req.State = StateExecuted
+	if status >= 400 {
+		req.State = StateFailed
+	}
+	return *req
+}
+
+// newID returns a random identifier for a change request
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate change request id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// approvedKey is the context key marking a call run after approval
+type approvedKey struct{}
+
+// NewContext returns a copy of ctx marking the call as approved by req
+func NewContext(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, approvedKey{}, req.ID)
+}
+
+// Approved reports whether the call in ctx runs after approval
+func Approved(ctx context.Context) bool {
+	_, ok := ctx.Value(approvedKey{}).(string)
+	return ok
+}
@@ -0,0 +1,130 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+)
+
+func newTestManager(t *testing.T, ttl time.Duration) *Manager {
+	t.Helper()
+
+	m, err := NewManager(config.ApprovalsConfig{TTL: ttl})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+func TestNewManager(t *testing.T) {
+	if _, err := NewManager(config.ApprovalsConfig{Operations: []string{"flush_root"}}); err == nil {
+		t.Error("NewManager accepted an unknown operation")
+	}
+
+	m, err := NewManager(config.ApprovalsConfig{Operations: []string{OpStop}, BulkThreshold: 2})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	if !m.Required(OpStop) || m.Required(OpReload) {
+		t.Error("Required does not follow the configured operations")
+	}
+	if m.Bulk(2) || !m.Bulk(3) {
+		t.Error("Bulk does not follow the configured threshold")
+	}
+}
+
+func TestApprove(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		approver string
+		wantErr  error
+	}{
+		{"different identity", "api_key", "bob", nil},
+		{"requester", "api_key", "alice", ErrSelfApproval},
+		{"same name, other method", "jwt", "alice", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t, time.Hour)
+			req, err := m.Create(Request{Operation: OpStop, RequestedBy: "alice", RequestedVia: "api_key"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			approved, err := m.Approve(req.ID, tt.method, tt.approver)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Approve error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if approved.State != StateApproved || approved.DecidedBy != tt.approver {
+				t.Errorf("request = %s by %q, want approved by %q", approved.State, approved.DecidedBy, tt.approver)
+			}
+			if _, err := m.Approve(req.ID, "api_key", "carol"); !errors.Is(err, ErrNotPending) {
+				t.Errorf("second Approve error = %v, want %v", err, ErrNotPending)
+			}
+		})
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	m := newTestManager(t, 10*time.Millisecond)
+	req, err := m.Create(Request{Operation: OpReload, RequestedBy: "alice", RequestedVia: "api_key"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if got, _ := m.Get(req.ID); got.State != StateExpired {
+		t.Errorf("state = %s, want %s", got.State, StateExpired)
+	}
+	if _, err := m.Approve(req.ID, "api_key", "bob"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Approve error = %v, want %v", err, ErrNotPending)
+	}
+	if _, err := m.Reject(req.ID, "bob"); !errors.Is(err, ErrNotPending) {
+		t.Errorf("Reject error = %v, want %v", err, ErrNotPending)
+	}
+	if list := m.List(StatePending); len(list) != 0 {
+		t.Errorf("List(pending) = %d requests, want none", len(list))
+	}
+}
+
+func TestComplete(t *testing.T) {
+	m := newTestManager(t, time.Hour)
+	req, _ := m.Create(Request{Operation: OpReload, RequestedBy: "alice", RequestedVia: "api_key"})
+	if _, err := m.Approve(req.ID, "api_key", "bob"); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+
+	if got := m.Complete(req.ID, 503, nil); got.State != StateFailed {
+		t.Errorf("state = %s, want %s", got.State, StateFailed)
+	}
+}
+
+func TestPruneDropsStaleApprovals(t *testing.T) {
+	m := newTestManager(t, time.Hour)
+	req, _ := m.Create(Request{Operation: OpReload, RequestedBy: "alice", RequestedVia: "api_key"})
+	if _, err := m.Approve(req.ID, "api_key", "bob"); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+
+	m.prune(time.Now().Add(retention + time.Hour))
+	if _, err := m.Get(req.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestApprovedContext(t *testing.T) {
+	ctx := context.Background()
+	if Approved(ctx) {
+		t.Error("plain context reported as approved")
+	}
+	if !Approved(NewContext(ctx, Request{ID: "1"})) {
+		t.Error("approved context not recognised")
+	}
+}
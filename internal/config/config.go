@@ -1,6 +1,10 @@
 package config
 
 import (
+	"reflect"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,22 +14,116 @@ type Config struct {
 	Security  SecurityConfig  `mapstructure:"security"`
 	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
 	Logging   LoggingConfig   `mapstructure:"logging"`
+	Health    HealthConfig    `mapstructure:"health"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Approvals ApprovalsConfig `mapstructure:"approvals"`
+	CORS      CORSConfig      `mapstructure:"cors"`
 }
 
 type ServerConfig struct {
-	Port     int    `mapstructure:"port"`
-	Host     string `mapstructure:"host"`
-	UseTLS   bool   `mapstructure:"use_tls"`
-	CertFile string `mapstructure:"cert_file"`
-	KeyFile  string `mapstructure:"key_file"`
+	Port         int           `mapstructure:"port"`
+	Host         string        `mapstructure:"host"`
+	UseTLS       bool          `mapstructure:"use_tls"`
+	CertFile     string        `mapstructure:"cert_file"`
+	KeyFile      string        `mapstructure:"key_file"`
+	ClientCAFile string        `mapstructure:"client_ca_file"`
+	ClientAuth   string        `mapstructure:"client_auth"`
+	HSTSMaxAge   time.Duration `mapstructure:"hsts_max_age"`
 }
 
 type UnboundConfig struct {
-	ControlSocket string `mapstructure:"control_socket"`
+	ControlSocket string        `mapstructure:"control_socket"`
+	ConfigFile    string        `mapstructure:"config_file"`
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+	PoolSize      int           `mapstructure:"pool_size"`
+	MaxIdle       time.Duration `mapstructure:"max_idle"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+	Retry         RetryConfig   `mapstructure:"retry"`
+	Breaker       BreakerConfig `mapstructure:"breaker"`
+	TraceLines    bool          `mapstructure:"trace_lines"`
+}
+
+type RetryConfig struct {
+	Attempts   int           `mapstructure:"attempts"`
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+}
+
+type BreakerConfig struct {
+	Threshold int           `mapstructure:"threshold"`
+	Cooldown  time.Duration `mapstructure:"cooldown"`
 }
 
 type SecurityConfig struct {
-	APIKey string `mapstructure:"api_key"`
+	APIKey      string             `mapstructure:"api_key" secret:"true"`
+	AdminAPIKey string             `mapstructure:"admin_api_key" secret:"true"`
+	APIKeys     []APIKeyConfig     `mapstructure:"api_keys"`
+	KeyStore    string             `mapstructure:"key_store"`
+	JWT         JWTConfig          `mapstructure:"jwt"`
+	Signing     SigningConfig      `mapstructure:"signing"`
+	ClientCerts []ClientCertConfig `mapstructure:"client_certs"`
+
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	AllowCIDRs     []string `mapstructure:"allow_cidrs"`
+	DenyCIDRs      []string `mapstructure:"deny_cidrs"`
+}
+
+type ClientCertConfig struct {
+	Name       string   `mapstructure:"name"`
+	CommonName string   `mapstructure:"common_name"`
+	DNSName    string   `mapstructure:"dns_name"`
+	URI        string   `mapstructure:"uri"`
+	Email      string   `mapstructure:"email"`
+	Roles      []string `mapstructure:"roles"`
+	Scopes     []string `mapstructure:"scopes"`
+}
+
+type APIKeyConfig struct {
+	Name       string   `mapstructure:"name"`
+	Key        string   `mapstructure:"key" secret:"true"`
+	KeyHash    string   `mapstructure:"key_hash"`
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	DenyCIDRs  []string `mapstructure:"deny_cidrs"`
+	Roles      []string `mapstructure:"roles"`
+	Scopes     []string `mapstructure:"scopes"`
+}
+
+type JWTConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	JWKSFile    string            `mapstructure:"jwks_file"`
+	JWKSURL     string            `mapstructure:"jwks_url"`
+	JWKSRefresh time.Duration     `mapstructure:"jwks_refresh"`
+	Issuer      string            `mapstructure:"issuer"`
+	Audience    string            `mapstructure:"audience"`
+	ClockSkew   time.Duration     `mapstructure:"clock_skew"`
+	Algorithms  []string          `mapstructure:"algorithms"`
+	NameClaim   string            `mapstructure:"name_claim"`
+	ScopeClaim  string            `mapstructure:"scope_claim"`
+	GroupsClaim string            `mapstructure:"groups_claim"`
+	GroupRoles  []GroupRoleConfig `mapstructure:"group_roles"`
+}
+
+type SigningConfig struct {
+	Enabled bool               `mapstructure:"enabled"`
+	Window  time.Duration      `mapstructure:"window"`
+	Keys    []SigningKeyConfig `mapstructure:"keys"`
+}
+
+type SigningKeyConfig struct {
+	ID         string   `mapstructure:"id"`
+	Secret     string   `mapstructure:"secret" secret:"true"`
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	DenyCIDRs  []string `mapstructure:"deny_cidrs"`
+	Roles      []string `mapstructure:"roles"`
+	Scopes     []string `mapstructure:"scopes"`
+}
+
+type GroupRoleConfig struct {
+	Group  string   `mapstructure:"group"`
+	Roles  []string `mapstructure:"roles"`
+	Scopes []string `mapstructure:"scopes"`
 }
 
 type RateLimitConfig struct {
@@ -34,21 +132,96 @@ type RateLimitConfig struct {
 }
 
 type LoggingConfig struct {
-	Level     string `mapstructure:"level"`
-	UseSyslog bool   `mapstructure:"use_syslog"`
-	AppName   string `mapstructure:"app_name"`
+	Level     string        `mapstructure:"level"`
+	Format    string        `mapstructure:"format"`
+	Outputs   []string      `mapstructure:"outputs"`
+	UseSyslog bool          `mapstructure:"use_syslog"`
+	AppName   string        `mapstructure:"app_name"`
+	File      LogFileConfig `mapstructure:"file"`
+	Redact    RedactConfig  `mapstructure:"redact"`
+}
+
+type LogFileConfig struct {
+	Path           string        `mapstructure:"path"`
+	MaxSizeMB      int           `mapstructure:"max_size_mb"`
+	RotateInterval time.Duration `mapstructure:"rotate_interval"`
+	MaxAge         time.Duration `mapstructure:"max_age"`
+	MaxBackups     int           `mapstructure:"max_backups"`
+	Compress       bool          `mapstructure:"compress"`
 }
 
+type RedactConfig struct {
+	Headers      []string `mapstructure:"headers"`
+	Fields       []string `mapstructure:"fields"`
+	MaxBodyBytes int      `mapstructure:"max_body_bytes"`
+}
+
+type HealthConfig struct {
+	CacheTTL          time.Duration `mapstructure:"cache_ttl"`
+	CertExpiryWarning time.Duration `mapstructure:"cert_expiry_warning"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `mapstructure:"exporter"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"service_name"`
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+type AdminConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Listen      string `mapstructure:"listen"`
+	AllowRemote bool   `mapstructure:"allow_remote"`
+}
+
+type AuditConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Path    string `mapstructure:"path"`
+}
+
+type ApprovalsConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	TTL           time.Duration `mapstructure:"ttl"`
+	Operations    []string      `mapstructure:"operations"`
+	BulkThreshold int           `mapstructure:"bulk_threshold"`
+}
+
+type CORSConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string      `mapstructure:"exposed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
+}
+
+// DefaultPath is the configuration file read when no path is given
+const DefaultPath = "config.yaml"
+
+// LoadConfig reads the configuration file at path. Every key can be
+// overridden by an environment variable named after it (see EnvPrefix), and
+// secrets can be read from files named by their *_file variants.
 func LoadConfig(path string) (*Config, error) {
-	viper.SetConfigFile(path)
-	viper.AutomaticEnv()
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	if err := bindEnv(v, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		return nil, err
 	}
 
 	config := &Config{}
-	if err := viper.Unmarshal(config); err != nil {
+	if err := v.Unmarshal(config); err != nil {
+		return nil, err
+	}
+	if err := loadSecretFiles(reflect.ValueOf(config).Elem(), v.AllSettings(), "", true); err != nil {
 		return nil, err
 	}
 
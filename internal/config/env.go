@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// EnvPrefix starts every environment variable read by the API. Nested
+	// keys join their parts with underscores, so security.api_key is read
+	// from UNBOUND_API_SECURITY_API_KEY.
+	EnvPrefix = "UNBOUND_API"
+
+	// fileSuffix marks a key or variable holding the path of a file with the
+	// value of a secret, e.g. api_key_file or UNBOUND_API_SECURITY_API_KEY_FILE
+	fileSuffix = "_file"
+)
+
+// bindEnv registers every key of the configuration struct t with v, so
+// environment variables are honoured even for keys missing from the file.
+// Lists of tables such as security.api_keys can only be set in the file.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			if err := bindEnv(v, field.Type, key+"."); err != nil {
+				return err
+			}
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			// Not representable in a single variable
+		default:
+			if err := v.BindEnv(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// envName returns the environment variable for a configuration key
+func envName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// loadSecretFiles fills the secret fields of the struct v from files. For a
+// secret at key k, a path can be given in the variable for k+"_file" or in
+// the key k+"_file" next to it in the configuration file. Setting both the
+// value and the path in the environment, or both in the file, is an error;
+// otherwise the environment takes precedence over the file as for any other
+// key. raw holds the settings of the same table as read by viper and prefix
+// the table's key. Entries of lists have no environment variables.
+func loadSecretFiles(v reflect.Value, raw map[string]interface{}, prefix string, env bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		value := v.Field(i)
+
+		switch {
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.String:
+			path, err := secretFile(key, value.String(), raw[name+fileSuffix], env)
+			if err != nil {
+				return err
+			}
+			if path == "" {
+				continue
+			}
+			secret, err := readSecretFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read %s%s: %w", key, fileSuffix, err)
+			}
+			value.SetString(secret)
+
+		case value.Kind() == reflect.Struct:
+			table, _ := raw[name].(map[string]interface{})
+			if err := loadSecretFiles(value, table, key+".", env); err != nil {
+				return err
+			}
+
+		case value.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			items, _ := raw[name].([]interface{})
+			for j := 0; j < value.Len() && j < len(items); j++ {
+				item, _ := items[j].(map[string]interface{})
+				if err := loadSecretFiles(value.Index(j), item, fmt.Sprintf("%s[%d].", key, j), false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// secretFile returns the file to read the secret at key from, or "" to keep
+// value. fromFile is the key+"_file" setting of the configuration file.
+func secretFile(key, value string, fromFile interface{}, env bool) (string, error) {
+	if env {
+		envValue := os.Getenv(envName(key))
+		envPath := os.Getenv(envName(key + fileSuffix))
+		switch {
+		case envValue != "" && envPath != "":
+			return "", fmt.Errorf("set either %s or %s, not both", envName(key), envName(key+fileSuffix))
+		case envPath != "":
+			return envPath, nil
+		case envValue != "":
+			// The variable overrides the file, including a path given there
+			return "", nil
+		}
+	}
+
+	path, _ := fromFile.(string)
+	if path != "" && value != "" {
+		return "", fmt.Errorf("set either %s or %s%s, not both", key, key, fileSuffix)
+	}
+	return path, nil
+}
+
+// readSecretFile reads a secret, dropping the trailing newline most tools add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
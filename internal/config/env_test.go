@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes content to name in dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", "server:\n  port: 8080\nsecurity:\n  api_key: from-file\n")
+
+	t.Setenv("UNBOUND_API_SERVER_PORT", "9090")
+	// Keys missing from the file are read too
+	t.Setenv("UNBOUND_API_SECURITY_JWT_CLOCK_SKEW", "45s")
+	t.Setenv("UNBOUND_API_SECURITY_TRUSTED_PROXIES", "10.0.0.0/8,192.0.2.1")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("server.port = %d, want 9090", cfg.Server.Port)
+	}
+	if cfg.Security.JWT.ClockSkew != 45*time.Second {
+		t.Errorf("security.jwt.clock_skew = %s, want 45s", cfg.Security.JWT.ClockSkew)
+	}
+	if want := []string{"10.0.0.0/8", "192.0.2.1"}; !reflect.DeepEqual(cfg.Security.TrustedProxies, want) {
+		t.Errorf("security.trusted_proxies = %q, want %q", cfg.Security.TrustedProxies, want)
+	}
+	if cfg.Security.APIKey != "from-file" {
+		t.Errorf("security.api_key = %q, want the value from the file", cfg.Security.APIKey)
+	}
+}
+
+func TestLoadConfigSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := writeFile(t, dir, "secret", "from-secret-file\n")
+	empty := writeFile(t, dir, "empty", "\n")
+	missing := filepath.Join(dir, "missing")
+
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		want    string
+		wantErr string
+	}{
+		{"value from the file", "security:\n  api_key: from-file\n", nil, "from-file", ""},
+		{"path in the file", "security:\n  api_key_file: " + secret + "\n", nil, "from-secret-file", ""},
+		{"path in the environment", "security:\n  api_key: from-file\n",
+			map[string]string{"UNBOUND_API_SECURITY_API_KEY_FILE": secret}, "from-secret-file", ""},
+		{"value in the environment over a path in the file", "security:\n  api_key_file: " + missing + "\n",
+			map[string]string{"UNBOUND_API_SECURITY_API_KEY": "from-env"}, "from-env", ""},
+		{"value and path in the file", "security:\n  api_key: from-file\n  api_key_file: " + secret + "\n", nil,
+			"", "security.api_key or security.api_key_file"},
+		{"value and path in the environment", "",
+			map[string]string{"UNBOUND_API_SECURITY_API_KEY": "from-env", "UNBOUND_API_SECURITY_API_KEY_FILE": secret},
+			"", "UNBOUND_API_SECURITY_API_KEY or UNBOUND_API_SECURITY_API_KEY_FILE"},
+		{"unreadable file", "security:\n  api_key_file: " + missing + "\n", nil, "", "security.api_key_file"},
+		{"empty file", "", map[string]string{"UNBOUND_API_SECURITY_API_KEY_FILE": empty}, "", "is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := LoadConfig(writeFile(t, t.TempDir(), "config.yaml", "server:\n  port: 8080\n"+tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig error = %v, want one mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if cfg.Security.APIKey != tt.want {
+				t.Errorf("security.api_key = %q, want %q", cfg.Security.APIKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSecretFilesInLists(t *testing.T) {
+	dir := t.TempDir()
+	secret := writeFile(t, dir, "ci", "ci-secret\n")
+	path := writeFile(t, dir, "config.yaml", "security:\n  api_keys:\n    - name: ci\n      key_file: "+secret+"\n      roles: [viewer]\n    - name: plain\n      key: plain-secret\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.Security.APIKeys) != 2 || cfg.Security.APIKeys[0].Key != "ci-secret" || cfg.Security.APIKeys[1].Key != "plain-secret" {
+		t.Errorf("security.api_keys = %+v, want keys ci-secret and plain-secret", cfg.Security.APIKeys)
+	}
+
+	both := writeFile(t, dir, "both.yaml", "security:\n  api_keys:\n    - name: ci\n      key: ci\n      key_file: "+secret+"\n")
+	if _, err := LoadConfig(both); err == nil {
+		t.Error("LoadConfig accepted an api_keys entry with both key and key_file")
+	}
+}
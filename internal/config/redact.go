@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// redactedValue replaces secrets in the output of Redacted
+const redactedValue = "[REDACTED]"
+
+// Redacted returns the configuration as a map keyed by the configuration file
+// names, with every field tagged `secret:"true"` replaced by a placeholder.
+// Empty secrets are left empty so it is still visible whether one is set.
+func Redacted(cfg *Config) map[string]interface{} {
+	return redactStruct(reflect.ValueOf(cfg).Elem())
+}
+
+func redactStruct(v reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{}, v.NumField())
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		out[name] = redactValue(v.Field(i), field.Tag.Get("secret") == "true")
+	}
+
+	return out
+}
+
+func redactValue(v reflect.Value, secret bool) interface{} {
+	if secret {
+		if v.IsZero() {
+			return v.Interface()
+		}
+		return redactedValue
+	}
+
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return redactStruct(v)
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = redactValue(v.Index(i), false)
+		}
+		return items
+	case reflect.Map:
+		items := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items[iter.Key().String()] = redactValue(iter.Value(), false)
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// AuditHandler serves the audit log
+type AuditHandler struct {
+	log *audit.Log
+}
+
+// NewAuditHandler creates a handler for the audit routes
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// timeParam parses an optional RFC 3339 query parameter
+func timeParam(r *http.Request, name string) (time.Time, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+// Records returns the most recent audit records matching the query filters
+func (h *AuditHandler) Records(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Identity: query.Get("identity"),
+		Route:    query.Get("route"),
+		Outcome:  query.Get("outcome"),
+	}
+
+	var ok bool
+	if filter.Since, ok = timeParam(r, "since"); !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid since, use RFC 3339")
+		return
+	}
+	if filter.Until, ok = timeParam(r, "until"); !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid until, use RFC 3339")
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > audit.MaxQueryLimit {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = n
+	}
+
+	records, err := h.log.Query(filter)
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    records,
+	})
+}
+
+// Verify checks the hash chain of the whole audit log
+func (h *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.log.Verify()
+	if err != nil {
+		respondWithError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: result.Valid,
+		Data:    result,
+	})
+}
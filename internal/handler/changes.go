@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/changes"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+// maxChangeBody bounds the request body kept with a change request
+const maxChangeBody = 64 * 1024
+
+// ChangesHandler turns calls to gated operations into change requests and
+// runs them once approved
+type ChangesHandler struct {
+	manager    *changes.Manager
+	operations map[string]gatedOperation
+}
+
+// gatedOperation is the handler of an operation and the scope needed to
+// request or approve it
+type gatedOperation struct {
+	scope string
+	run   http.HandlerFunc
+}
+
+// NewChangesHandler creates a handler for the change request routes
+func NewChangesHandler(manager *changes.Manager) *ChangesHandler {
+	return &ChangesHandler{
+		manager:    manager,
+		operations: make(map[string]gatedOperation),
+	}
+}
+
+// Gate wraps the handler of op. When op needs approval and match, if given,
+// accepts the request, the call is stored as a pending change request and
+// answered with 202 Accepted instead of running next. The approver must hold
+// scope, the scope the route requires of the requester.
+func (h *ChangesHandler) Gate(op, scope string, match func(*http.Request) bool, next http.HandlerFunc) http.HandlerFunc {
+	h.operations[op] = gatedOperation{scope: scope, run: next}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.manager.Required(op) || (match != nil && !match(r)) {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxChangeBody+1))
+		if err != nil || len(body) > maxChangeBody {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		identity := auth.FromContext(r.Context())
+		req, err := h.manager.Create(changes.Request{
+			Operation:    op,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Query:        r.URL.RawQuery,
+			Body:         body,
+			ContentType:  r.Header.Get("Content-Type"),
+			RequestedBy:  identity.Name,
+			RequestedVia: identity.Method,
+		})
+		if err != nil {
+			respondWithError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		info := changeInfo(req)
+		audit.RecordState(r.Context(), nil, info)
+
+		respondWithJSON(w, http.StatusAccepted, response.CommonResponse{
+			Success: true,
+			Data:    info,
+		})
+	}
+}
+
+// changeInfo converts a change request to its API representation
+func changeInfo(req changes.Request) response.ChangeRequest {
+	info := response.ChangeRequest{
+		ID:           req.ID,
+		Operation:    req.Operation,
+		Method:       req.Method,
+		Path:         req.Path,
+		Query:        req.Query,
+		RequestedBy:  req.RequestedBy,
+		RequestedVia: req.RequestedVia,
+		State:        req.State,
+		CreatedAt:    req.CreatedAt,
+		ExpiresAt:    req.ExpiresAt,
+		DecidedBy:    req.DecidedBy,
+		DecidedAt:    req.DecidedAt,
+	}
+	if req.Status != 0 {
+		result := &response.ChangeResult{Status: req.Status}
+		if json.Valid(req.Response) {
+			result.Response = req.Response
+		} else if len(req.Response) > 0 {
+			// Plain text from http.Error
+			result.Response, _ = json.Marshal(string(bytes.TrimSpace(req.Response)))
+		}
+		info.Result = result
+	}
+	return info
+}
+
+// respondWithChangeError maps change request errors to HTTP responses
+func respondWithChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, changes.ErrNotFound):
+		respondWithError(w, r, http.StatusNotFound, "Change request not found")
+	case errors.Is(err, changes.ErrNotPending):
+		respondWithError(w, r, http.StatusConflict, "Change request is no longer pending")
+	case errors.Is(err, changes.ErrSelfApproval):
+		respondWithError(w, r, http.StatusForbidden, "Change requests must be approved by a different identity")
+	default:
+		respondWithError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ListChanges returns the change requests, optionally only those in ?state=
+func (h *ChangesHandler) ListChanges(w http.ResponseWriter, r *http.Request) {
+	stored := h.manager.List(r.URL.Query().Get("state"))
+	list := make([]response.ChangeRequest, len(stored))
+	for i, req := range stored {
+		list[i] = changeInfo(req)
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+func (h *ChangesHandler) GetChange(w http.ResponseWriter, r *http.Request) {
+	req, err := h.manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		respondWithChangeError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    changeInfo(req),
+	})
+}
+
+// ApproveChange approves a pending change request and runs the stored call
+// on behalf of the requester. Besides changes:approve, the approver needs the
+// scope of the operation itself. The Unbound commands it sends are recorded
+// in the audit record of the approval.
+func (h *ChangesHandler) ApproveChange(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	identity := auth.FromContext(r.Context())
+
+	pending, err := h.manager.Get(id)
+	if err != nil {
+		respondWithChangeError(w, r, err)
+		return
+	}
+	op := h.operations[pending.Operation]
+	if !identity.Has(op.scope) {
+		respondWithError(w, r, http.StatusForbidden, "Approving this change request requires scope "+op.scope)
+		return
+	}
+
+	req, err := h.manager.Approve(id, identity.Method, identity.Name)
+	if err != nil {
+		respondWithChangeError(w, r, err)
+		return
+	}
+
+	target := req.Path
+	if req.Query != "" {
+		target += "?" + req.Query
+	}
+	call, err := http.NewRequestWithContext(changes.NewContext(r.Context(), req), req.Method, target, bytes.NewReader(req.Body))
+	if err != nil {
+		h.manager.Complete(req.ID, http.StatusInternalServerError, nil)
+		respondWithError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if req.ContentType != "" {
+		call.Header.Set("Content-Type", req.ContentType)
+	}
+
+	// Record a failure if the call panics, so the request does not stay
+	// approved forever
+	completed := false
+	defer func() {
+		if !completed {
+			h.manager.Complete(req.ID, http.StatusInternalServerError, nil)
+		}
+	}()
+
+	rec := &changeRecorder{header: make(http.Header), status: http.StatusOK}
+	op.run(rec, call)
+	req = h.manager.Complete(req.ID, rec.status, rec.body.Bytes())
+	completed = true
+
+	if rec.status >= http.StatusBadRequest {
+		respondWithJSON(w, rec.status, response.CommonResponse{
+			Success: false,
+			Data:    changeInfo(req),
+			Error: &response.Error{
+				Code:      errorCode(rec.status),
+				Message:   "Approved operation failed",
+				RequestID: logger.RequestID(r.Context()),
+			},
+		})
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    changeInfo(req),
+	})
+}
+
+// RejectChange rejects a pending change request. Besides approvers, the
+// requester may withdraw their own request.
+func (h *ChangesHandler) RejectChange(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	identity := auth.FromContext(r.Context())
+
+	before, err := h.manager.Get(id)
+	if err != nil {
+		respondWithChangeError(w, r, err)
+		return
+	}
+	if !before.IsRequester(identity.Method, identity.Name) && !identity.Has(auth.ScopeChangesApprove) {
+		respondWithError(w, r, http.StatusForbidden, "Only approvers or the requester may reject a change request")
+		return
+	}
+
+	req, err := h.manager.Reject(id, identity.Name)
+	if err != nil {
+		respondWithChangeError(w, r, err)
+		return
+	}
+	audit.RecordState(r.Context(), changeInfo(before), changeInfo(req))
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    changeInfo(req),
+	})
+}
+
+// changeRecorder captures the response of an approved call
+type changeRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (cr *changeRecorder) Header() http.Header {
+	return cr.header
+}
+
+func (cr *changeRecorder) WriteHeader(code int) {
+	cr.status = code
+}
+
+func (cr *changeRecorder) Write(b []byte) (int, error) {
+	return cr.body.Write(b)
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/changes"
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/gorilla/mux"
+)
+
+// stub is a gated operation that records how it was called
+type stub struct {
+	calls    int
+	body     string
+	approved bool
+	panics   bool
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.calls++
+	b, _ := io.ReadAll(r.Body)
+	s.body = string(b)
+	s.approved = changes.Approved(r.Context())
+	if s.panics {
+		panic("boom")
+	}
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{Success: true, Data: "done"})
+}
+
+func testIdentity(method, name string, scopes ...string) *auth.Identity {
+	granted := make(auth.Scopes)
+	for _, scope := range scopes {
+		granted[scope] = true
+	}
+	return &auth.Identity{Name: name, Method: method, Scopes: granted}
+}
+
+// serve calls h as identity with the path variable id, if given
+func serve(h http.HandlerFunc, method, target, body string, who *auth.Identity, id string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(auth.NewContext(r.Context(), who))
+	if id != "" {
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+	}
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	return rec
+}
+
+// changeID returns the id of the change request in a 202 response
+func changeID(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var body struct {
+		Data response.ChangeRequest `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data.ID == "" {
+		t.Fatalf("response %q has no change request: %v", rec.Body, err)
+	}
+	return body.Data.ID
+}
+
+func newTestChangesHandler(t *testing.T, op *stub, match func(*http.Request) bool) (*ChangesHandler, http.HandlerFunc) {
+	t.Helper()
+
+	m, err := changes.NewManager(config.ApprovalsConfig{})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	h := NewChangesHandler(m)
+	return h, h.Gate(changes.OpStop, auth.ScopeServerStop, match, op.ServeHTTP)
+}
+
+var (
+	requester = testIdentity(auth.MethodAPIKey, "alice", auth.ScopeServerStop)
+	approver  = testIdentity(auth.MethodAPIKey, "bob", auth.ScopeChangesApprove, auth.ScopeServerStop)
+)
+
+func TestGateAndApprove(t *testing.T) {
+	op := &stub{}
+	h, gated := newTestChangesHandler(t, op, nil)
+
+	rec := serve(gated, http.MethodPost, "/api/v1/stop?x=1", `{"a":1}`, requester, "")
+	if rec.Code != http.StatusAccepted || op.calls != 0 {
+		t.Fatalf("gated call = %d with %d runs, want 202 without running", rec.Code, op.calls)
+	}
+	id := changeID(t, rec)
+
+	rec = serve(h.ApproveChange, http.MethodPost, "/", "", approver, id)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("approve = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if op.calls != 1 || op.body != `{"a":1}` || !op.approved {
+		t.Errorf("replayed call = %+v, want one approved call with the stored body", op)
+	}
+	if req, _ := h.manager.Get(id); req.State != changes.StateExecuted || req.Status != http.StatusOK {
+		t.Errorf("request = %s/%d, want executed/200", req.State, req.Status)
+	}
+}
+
+func TestGateMatchBypass(t *testing.T) {
+	op := &stub{}
+	h, gated := newTestChangesHandler(t, op, func(*http.Request) bool { return false })
+
+	rec := serve(gated, http.MethodPost, "/api/v1/stop", "", requester, "")
+	if rec.Code != http.StatusOK || op.calls != 1 {
+		t.Errorf("call = %d with %d runs, want 200 run directly", rec.Code, op.calls)
+	}
+	if list := h.manager.List(""); len(list) != 0 {
+		t.Errorf("%d change requests created, want none", len(list))
+	}
+}
+
+func TestApproveRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		who  *auth.Identity
+	}{
+		{"requester", testIdentity(auth.MethodAPIKey, "alice", auth.ScopeChangesApprove, auth.ScopeServerStop)},
+		{"approver without operation scope", testIdentity(auth.MethodAPIKey, "bob", auth.ScopeChangesApprove)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &stub{}
+			h, gated := newTestChangesHandler(t, op, nil)
+			id := changeID(t, serve(gated, http.MethodPost, "/api/v1/stop", "", requester, ""))
+
+			rec := serve(h.ApproveChange, http.MethodPost, "/", "", tt.who, id)
+			if rec.Code != http.StatusForbidden || op.calls != 0 {
+				t.Errorf("approve = %d with %d runs, want 403 without running", rec.Code, op.calls)
+			}
+			if req, _ := h.manager.Get(id); req.State != changes.StatePending {
+				t.Errorf("state = %s, want %s", req.State, changes.StatePending)
+			}
+		})
+	}
+}
+
+func TestReject(t *testing.T) {
+	tests := []struct {
+		name string
+		who  *auth.Identity
+		want int
+	}{
+		{"requester withdraws", requester, http.StatusOK},
+		{"approver", approver, http.StatusOK},
+		{"third party", testIdentity(auth.MethodAPIKey, "carol", auth.ScopeChangesRead), http.StatusForbidden},
+		{"requester name under other method", testIdentity(auth.MethodJWT, "alice", auth.ScopeChangesRead), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, gated := newTestChangesHandler(t, &stub{}, nil)
+			id := changeID(t, serve(gated, http.MethodPost, "/api/v1/stop", "", requester, ""))
+
+			if rec := serve(h.RejectChange, http.MethodPost, "/", "", tt.who, id); rec.Code != tt.want {
+				t.Errorf("reject = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovePanicCompletes(t *testing.T) {
+	op := &stub{panics: true}
+	h, gated := newTestChangesHandler(t, op, nil)
+	id := changeID(t, serve(gated, http.MethodPost, "/api/v1/stop", "", requester, ""))
+
+	func() {
+		defer func() { recover() }()
+		serve(h.ApproveChange, http.MethodPost, "/", "", approver, id)
+	}()
+
+	if req, _ := h.manager.Get(id); req.State != changes.StateFailed || req.Status != http.StatusInternalServerError {
+		t.Errorf("request = %s/%d, want failed/500", req.State, req.Status)
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// cookieSecretRequest is the body accepted when adding a cookie secret
+type cookieSecretRequest struct {
+	Secret string `json:"secret"`
+}
+
+// cookieSecretLength is the length of a hex encoded 128-bit cookie secret
+const cookieSecretLength = 32
+
+// isCookieSecret reports whether s is a hex encoded 128-bit secret
+func isCookieSecret(s string) bool {
+	if len(s) != cookieSecretLength {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// cookieFingerprint returns a short digest that identifies a secret without revealing it
+func cookieFingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (h *UnboundHandler) CookieSecrets(w http.ResponseWriter, r *http.Request) {
+	secrets, err := h.client().CookieSecrets(r.Context())
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	elevated := auth.HasScope(r.Context(), auth.ScopeCookieSecrets)
+	for i := range secrets {
+		secrets[i].Fingerprint = cookieFingerprint(secrets[i].Secret)
+		if !elevated {
+			secrets[i].Secret = ""
+			secrets[i].Redacted = true
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    secrets,
+	})
+}
+
+func (h *UnboundHandler) AddCookieSecret(w http.ResponseWriter, r *http.Request) {
+	var req cookieSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !isCookieSecret(req.Secret) {
+		respondWithError(w, r, http.StatusBadRequest, "Secret must be 32 hexadecimal characters")
+		return
+	}
+
+	if err := h.client().AddCookieSecret(r.Context(), req.Secret); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Cookie secret added as staging secret",
+	})
+}
+
+func (h *UnboundHandler) ActivateCookieSecret(w http.ResponseWriter, r *http.Request) {
+	if err := h.client().ActivateCookieSecret(r.Context()); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Staging cookie secret activated",
+	})
+}
+
+func (h *UnboundHandler) DropCookieSecret(w http.ResponseWriter, r *http.Request) {
+	if err := h.client().DropCookieSecret(r.Context()); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Staging cookie secret dropped",
+	})
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/internal/unbound"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+)
+
+const (
+	// defaultCertExpiryWarning is used when health.cert_expiry_warning is not set
+	defaultCertExpiryWarning = 7 * 24 * time.Hour
+
+	// readinessTimeout bounds a run of the readiness checks
+	readinessTimeout = 5 * time.Second
+
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+// HealthHandler serves the unauthenticated liveness and readiness probes
+type HealthHandler struct {
+	client  func() *unbound.Client
+	config  func() *config.Config
+	started time.Time
+
+	mu        sync.Mutex
+	cached    *response.ReadinessResponse
+	checkedAt time.Time
+	running   chan struct{}
+}
+
+// NewHealthHandler creates the probe handler. Readiness results are cached for
+// health.cache_ttl so frequent probes do not hammer the control socket.
+// client and config return the current Unbound client and configuration, so
+// the checks follow a reload.
+func NewHealthHandler(client func() *unbound.Client, config func() *config.Config) *HealthHandler {
+	return &HealthHandler{
+		client:  client,
+		config:  config,
+		started: time.Now(),
+	}
+}
+
+// Liveness reports that the process is up and serving requests
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, response.LivenessResponse{
+		Status: checkOK,
+		Uptime: time.Since(h.started).Round(time.Second).String(),
+	})
+}
+
+// Readiness reports whether the API can serve traffic: the configuration is
+// loaded, the control socket answers and the TLS certificate is usable
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ready := h.readiness(r.Context())
+
+	code := http.StatusOK
+	if ready.Status != "ready" {
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, ready)
+}
+
+// readiness returns the cached result or runs the checks again. Only one run
+// is in flight at a time; concurrent probes wait for its result. The run is
+// detached from the probe, so a probe giving up neither aborts it nor leaves
+// a failure caused by its own cancellation in the cache.
+func (h *HealthHandler) readiness(ctx context.Context) *response.ReadinessResponse {
+	h.mu.Lock()
+	if h.cached != nil && time.Since(h.checkedAt) < h.config().Health.CacheTTL {
+		defer h.mu.Unlock()
+		return h.cached
+	}
+	running := h.running
+	if running == nil {
+		running = make(chan struct{})
+		h.running = running
+		go h.check(running)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-running:
+	case <-ctx.Done():
+		return &response.ReadinessResponse{
+			Status:    "not_ready",
+			CheckedAt: time.Now(),
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.cached
+}
+
+// check runs the readiness checks, stores the result and closes done
+func (h *HealthHandler) check(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+	defer cancel()
+
+	cfg := h.config()
+	checks := map[string]response.HealthCheck{
+		"config":         checkConfig(cfg),
+		"control_socket": h.checkControlSocket(ctx),
+	}
+	if cfg != nil && cfg.Server.UseTLS {
+		checks["tls_certificate"] = checkCertificate(cfg)
+	}
+
+	status := "ready"
+	for _, check := range checks {
+		if check.Status != checkOK {
+			status = "not_ready"
+			break
+		}
+	}
+
+	h.mu.Lock()
+	h.checkedAt = time.Now()
+	h.cached = &response.ReadinessResponse{
+		Status:    status,
+		Checks:    checks,
+		CheckedAt: h.checkedAt,
+	}
+	h.running = nil
+	h.mu.Unlock()
+	close(done)
+}
+
+// The probes are unauthenticated, so failed checks report a generic message
+// and the details only go to the log
+
+func checkConfig(cfg *config.Config) response.HealthCheck {
+	if cfg == nil {
+		return response.HealthCheck{Status: checkFail, Message: "configuration not loaded"}
+	}
+	return response.HealthCheck{Status: checkOK}
+}
+
+func (h *HealthHandler) checkControlSocket(ctx context.Context) response.HealthCheck {
+	if err := h.client().TestConnection(ctx); err != nil {
+		logger.Get().Warn().Err(err).Msg("readiness: control socket check failed")
+		return response.HealthCheck{Status: checkFail, Message: "control socket unreachable"}
+	}
+	return response.HealthCheck{Status: checkOK}
+}
+
+func checkCertificate(cfg *config.Config) response.HealthCheck {
+	cert, err := tls.LoadX509KeyPair(cfg.Server.CertFile, cfg.Server.KeyFile)
+	if err != nil {
+		logger.Get().Warn().Err(err).Msg("readiness: certificate check failed")
+		return response.HealthCheck{Status: checkFail, Message: "certificate could not be loaded"}
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		logger.Get().Warn().Err(err).Msg("readiness: certificate check failed")
+		return response.HealthCheck{Status: checkFail, Message: "certificate could not be parsed"}
+	}
+
+	expiryWarning := cfg.Health.CertExpiryWarning
+	if expiryWarning <= 0 {
+		expiryWarning = defaultCertExpiryWarning
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(leaf.NotBefore):
+		return response.HealthCheck{Status: checkFail, Message: "certificate is not yet valid", ExpiresAt: &leaf.NotAfter}
+	case now.After(leaf.NotAfter):
+		return response.HealthCheck{Status: checkFail, Message: "certificate has expired", ExpiresAt: &leaf.NotAfter}
+	case leaf.NotAfter.Sub(now) < expiryWarning:
+		return response.HealthCheck{
+			Status:    checkFail,
+			Message:   fmt.Sprintf("certificate expires in %s", leaf.NotAfter.Sub(now).Round(time.Hour)),
+			ExpiresAt: &leaf.NotAfter,
+		}
+	}
+	return response.HealthCheck{Status: checkOK, ExpiresAt: &leaf.NotAfter}
+}
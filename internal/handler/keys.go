@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/gorilla/mux"
+)
+
+// KeysHandler manages the API keys in the key store
+type KeysHandler struct {
+	store *auth.Store
+}
+
+// NewKeysHandler creates a handler for the key management routes
+func NewKeysHandler(store *auth.Store) *KeysHandler {
+	return &KeysHandler{store: store}
+}
+
+// createKeyRequest is the body accepted when creating a key. ExpiresAt and
+// TTL are alternatives, without either the key does not expire.
+type createKeyRequest struct {
+	Name       string     `json:"name"`
+	Roles      []string   `json:"roles"`
+	Scopes     []string   `json:"scopes"`
+	AllowCIDRs []string   `json:"allow_cidrs"`
+	DenyCIDRs  []string   `json:"deny_cidrs"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	TTL        string     `json:"ttl"`
+}
+
+// rotateKeyRequest is the optional body accepted when rotating a key
+type rotateKeyRequest struct {
+	Grace string `json:"grace"`
+}
+
+// expireKeyRequest is the optional body accepted when expiring a key.
+// Without either field the key expires immediately.
+type expireKeyRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	TTL       string     `json:"ttl"`
+}
+
+// decodeOptional decodes a JSON body that may be empty
+func decodeOptional(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// expiry resolves an absolute or relative expiry, returning nil for neither
+func expiry(at *time.Time, ttl string) (*time.Time, bool) {
+	if at != nil && ttl != "" {
+		return nil, false
+	}
+	if ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return nil, false
+		}
+		t := time.Now().Add(d).UTC()
+		return &t, true
+	}
+	return at, true
+}
+
+// keyInfo converts a stored key to its API representation
+func keyInfo(k auth.StoredKey, secret string) response.APIKey {
+	return response.APIKey{
+		ID:                k.ID,
+		Name:              k.Name,
+		Roles:             k.Roles,
+		Scopes:            k.Scopes,
+		AllowCIDRs:        k.AllowCIDRs,
+		DenyCIDRs:         k.DenyCIDRs,
+		State:             k.State(time.Now()),
+		CreatedAt:         k.CreatedAt,
+		RotatedAt:         k.RotatedAt,
+		ExpiresAt:         k.ExpiresAt,
+		RevokedAt:         k.RevokedAt,
+		PreviousExpiresAt: k.PreviousExpiresAt,
+		Secret:            secret,
+	}
+}
+
+// respondWithKeyError maps key store errors to HTTP responses
+func respondWithKeyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		respondWithError(w, r, http.StatusNotFound, "API key not found")
+	case errors.Is(err, auth.ErrKeyExists):
+		respondWithError(w, r, http.StatusConflict, "An active API key with this name already exists")
+	case errors.Is(err, auth.ErrKeyRevoked):
+		respondWithError(w, r, http.StatusConflict, "API key is revoked")
+	case errors.Is(err, auth.ErrKeyExpired):
+		respondWithError(w, r, http.StatusConflict, "API key has expired")
+	default:
+		respondWithError(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// withinCallerScopes reports whether the caller holds every scope granted by
+// roles and scopes, answering 403 if not. Without this check keys:manage
+// could mint or take over keys with more access than the caller has.
+func withinCallerScopes(w http.ResponseWriter, r *http.Request, roles, scopes []string) bool {
+	granted, err := auth.ResolveScopes(roles, scopes)
+	if err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return false
+	}
+	identity := auth.FromContext(r.Context())
+	if identity == nil || !identity.Scopes.Covers(granted) {
+		respondWithError(w, r, http.StatusForbidden, "Cannot manage a key with scopes the caller does not have")
+		return false
+	}
+	return true
+}
+
+// targetKey looks up the key named in the path and checks that the caller
+// may manage it
+func (h *KeysHandler) targetKey(w http.ResponseWriter, r *http.Request) (auth.StoredKey, bool) {
+	key, ok := h.store.Get(mux.Vars(r)["id"])
+	if !ok {
+		respondWithKeyError(w, r, auth.ErrKeyNotFound)
+		return auth.StoredKey{}, false
+	}
+	if !withinCallerScopes(w, r, key.Roles, key.Scopes) {
+		return auth.StoredKey{}, false
+	}
+	return key, true
+}
+
+func (h *KeysHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	stored := h.store.List()
+	keys := make([]response.APIKey, len(stored))
+	for i, k := range stored {
+		keys[i] = keyInfo(k, "")
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    keys,
+	})
+}
+
+// CreateKey creates a key and returns its secret. The secret is not stored
+// and is only ever returned by this response.
+func (h *KeysHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !isToken(req.Name) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid key name")
+		return
+	}
+	if len(req.Roles) == 0 && len(req.Scopes) == 0 {
+		respondWithError(w, r, http.StatusBadRequest, "At least one role or scope is required")
+		return
+	}
+	if !withinCallerScopes(w, r, req.Roles, req.Scopes) {
+		return
+	}
+	if _, err := auth.NewIPPolicy(req.AllowCIDRs, req.DenyCIDRs); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	expiresAt, ok := expiry(req.ExpiresAt, req.TTL)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Use either expires_at or a positive ttl")
+		return
+	}
+
+	key, secret, err := h.store.Create(auth.NewKey{
+		Name:       req.Name,
+		Roles:      req.Roles,
+		Scopes:     req.Scopes,
+		AllowCIDRs: req.AllowCIDRs,
+		DenyCIDRs:  req.DenyCIDRs,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		respondWithKeyError(w, r, err)
+		return
+	}
+	audit.RecordState(r.Context(), nil, keyInfo(key, ""))
+
+	respondWithJSON(w, http.StatusCreated, response.CommonResponse{
+		Success: true,
+		Data:    keyInfo(key, secret),
+	})
+}
+
+// RotateKey replaces the secret of a key. With grace the old secret keeps
+// working for that long so clients can switch over.
+func (h *KeysHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	var req rotateKeyRequest
+	if err := decodeOptional(r, &req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	var grace time.Duration
+	if req.Grace != "" {
+		d, err := time.ParseDuration(req.Grace)
+		if err != nil || d < 0 {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid grace duration")
+			return
+		}
+		grace = d
+	}
+
+	before, ok := h.targetKey(w, r)
+	if !ok {
+		return
+	}
+	key, secret, err := h.store.Rotate(before.ID, grace)
+	if err != nil {
+		respondWithKeyError(w, r, err)
+		return
+	}
+	audit.RecordState(r.Context(), keyInfo(before, ""), keyInfo(key, ""))
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    keyInfo(key, secret),
+	})
+}
+
+func (h *KeysHandler) ExpireKey(w http.ResponseWriter, r *http.Request) {
+	var req expireKeyRequest
+	if err := decodeOptional(r, &req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	expiresAt, ok := expiry(req.ExpiresAt, req.TTL)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Use either expires_at or a positive ttl")
+		return
+	}
+	at := time.Now()
+	if expiresAt != nil {
+		at = *expiresAt
+	}
+
+	before, ok := h.targetKey(w, r)
+	if !ok {
+		return
+	}
+	key, err := h.store.Expire(before.ID, at)
+	if err != nil {
+		respondWithKeyError(w, r, err)
+		return
+	}
+	audit.RecordState(r.Context(), keyInfo(before, ""), keyInfo(key, ""))
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    keyInfo(key, ""),
+	})
+}
+
+func (h *KeysHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	before, ok := h.targetKey(w, r)
+	if !ok {
+		return
+	}
+	key, err := h.store.Revoke(before.ID)
+	if err != nil {
+		respondWithKeyError(w, r, err)
+		return
+	}
+	audit.RecordState(r.Context(), keyInfo(before, ""), keyInfo(key, ""))
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    keyInfo(key, ""),
+	})
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/changes"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+const (
+	// reloadVerifyTimeout bounds how long a verified reload waits for Unbound
+	reloadVerifyTimeout = 15 * time.Second
+
+	// stopTokenTTL is how long a stop confirmation token stays valid
+	stopTokenTTL = time.Minute
+)
+
+// stopGuard hands out single-use confirmation tokens for the stop endpoint.
+// Each identity has its own token, so callers cannot invalidate or use each
+// other's.
+type stopGuard struct {
+	mu     sync.Mutex
+	tokens map[string]stopToken
+}
+
+// stopToken is an outstanding confirmation token
+type stopToken struct {
+	token   string
+	expires time.Time
+}
+
+// stopOwner returns the key of the caller's token
+func stopOwner(r *http.Request) string {
+	identity := auth.FromContext(r.Context())
+	if identity == nil {
+		return ""
+	}
+	return identity.Method + "\x00" + identity.Name
+}
+
+// issue creates a new token for owner, replacing any outstanding one
+func (g *stopGuard) issue(owner string) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if g.tokens == nil {
+		g.tokens = make(map[string]stopToken)
+	}
+	for o, t := range g.tokens {
+		if now.After(t.expires) {
+			delete(g.tokens, o)
+		}
+	}
+	t := stopToken{token: hex.EncodeToString(buf), expires: now.Add(stopTokenTTL)}
+	g.tokens[owner] = t
+	return t.token, t.expires, nil
+}
+
+// consume reports whether token matches owner's outstanding token and
+// invalidates it
+func (g *stopGuard) consume(owner, token string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tokens[owner]
+	if !ok || time.Now().After(t.expires) {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(t.token)) != 1 {
+		return false
+	}
+	delete(g.tokens, owner)
+	return true
+}
+
+// boolParam parses an optional boolean query parameter
+func boolParam(r *http.Request, name string) (bool, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, true
+	}
+	b, err := strconv.ParseBool(v)
+	return b, err == nil
+}
+
+// Stop stops Unbound in two steps. A request without a "confirm" parameter
+// returns a short-lived token; repeating the request with confirm=<token>
+// actually sends the stop command. An approved change request stands in for
+// the confirmation.
+func (h *UnboundHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	approved := changes.Approved(r.Context())
+	confirm := r.URL.Query().Get("confirm")
+	if confirm == "" && !approved {
+		token, expires, err := h.stop.issue(stopOwner(r))
+		if err != nil {
+			respondWithClientError(w, r, err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusAccepted, response.CommonResponse{
+			Success: true,
+			Data: response.StopConfirmation{
+				Token:     token,
+				ExpiresAt: expires,
+				Message:   "Repeat the request with confirm=<token> to stop Unbound",
+			},
+		})
+		return
+	}
+
+	if !approved && !h.stop.consume(stopOwner(r), confirm) {
+		respondWithError(w, r, http.StatusForbidden, "Invalid or expired confirmation token")
+		return
+	}
+
+	if err := h.client().Stop(r.Context()); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Unbound stopped",
+	})
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/internal/unbound"
+)
+
+// localZoneRequest is the body accepted when adding a local zone
+type localZoneRequest struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	View string `json:"view"`
+}
+
+// localDataRequest is the body accepted when adding local data
+type localDataRequest struct {
+	Data string `json:"data"`
+	View string `json:"view"`
+}
+
+// validLocalZoneTypes are the local-zone types accepted by Unbound
+var validLocalZoneTypes = map[string]bool{
+	"deny": true, "refuse": true, "static": true, "transparent": true,
+	"typetransparent": true, "redirect": true, "nodefault": true,
+	"inform": true, "inform_deny": true, "inform_redirect": true,
+	"always_transparent": true, "block_a": true, "always_refuse": true,
+	"always_nxdomain": true, "always_null": true, "noview": true,
+}
+
+// isToken reports whether s is safe to pass as a single control command argument
+func isToken(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\r\n")
+}
+
+// nameParams returns the names given in one or more "name" parameters, or
+// false if there are none or one is not a valid token
+func nameParams(r *http.Request) ([]string, bool) {
+	names := r.URL.Query()["name"]
+	if len(names) == 0 {
+		return nil, false
+	}
+	for _, name := range names {
+		if !isToken(name) {
+			return nil, false
+		}
+	}
+	return names, true
+}
+
+// viewParam returns the optional view from the query string and whether it is valid
+func viewParam(r *http.Request) (string, bool) {
+	view := r.URL.Query().Get("view")
+	return view, view == "" || isToken(view)
+}
+
+func (h *UnboundHandler) ListLocalZones(w http.ResponseWriter, r *http.Request) {
+	view, ok := viewParam(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+
+	zones, err := h.client().ListLocalZones(r.Context(), view)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    zones,
+	})
+}
+
+func (h *UnboundHandler) AddLocalZone(w http.ResponseWriter, r *http.Request) {
+	var req localZoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !isToken(req.Name) {
+		respondWithError(w, r, http.StatusBadRequest, "Zone name is required")
+		return
+	}
+	if !validLocalZoneTypes[req.Type] {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid local zone type")
+		return
+	}
+	if req.View != "" && !isToken(req.View) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+
+	if err := h.client().AddLocalZone(r.Context(), req.View, req.Name, req.Type); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Local zone added successfully",
+	})
+}
+
+func (h *UnboundHandler) RemoveLocalZone(w http.ResponseWriter, r *http.Request) {
+	view, ok := viewParam(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+	names, ok := nameParams(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Zone name is required")
+		return
+	}
+
+	var before []response.LocalZone
+	for _, name := range names {
+		if audit.Enabled(r.Context()) {
+			before = append(before, h.localZonesNamed(r.Context(), view, name)...)
+		}
+		if err := h.client().RemoveLocalZone(r.Context(), view, name); err != nil {
+			respondWithClientError(w, r, err)
+			return
+		}
+	}
+	audit.RecordState(r.Context(), before, nil)
+
+	message := "Local zone removed successfully"
+	if len(names) > 1 {
+		message = "Local zones removed successfully"
+	}
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    message,
+	})
+}
+
+func (h *UnboundHandler) ListLocalData(w http.ResponseWriter, r *http.Request) {
+	view, ok := viewParam(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+
+	records, err := h.client().ListLocalData(r.Context(), view)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    records,
+	})
+}
+
+func (h *UnboundHandler) AddLocalData(w http.ResponseWriter, r *http.Request) {
+	var req localDataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// The record is sent verbatim, so it must stay on a single line
+	req.Data = strings.TrimSpace(req.Data)
+	if req.Data == "" || strings.ContainsAny(req.Data, "\r\n") {
+		respondWithError(w, r, http.StatusBadRequest, "Record data is required")
+		return
+	}
+	if req.View != "" && !isToken(req.View) {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+
+	if err := h.client().AddLocalData(r.Context(), req.View, req.Data); err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Local data added successfully",
+	})
+}
+
+func (h *UnboundHandler) RemoveLocalData(w http.ResponseWriter, r *http.Request) {
+	view, ok := viewParam(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid view name")
+		return
+	}
+	names, ok := nameParams(r)
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	var before []response.LocalData
+	for _, name := range names {
+		if audit.Enabled(r.Context()) {
+			before = append(before, h.localDataNamed(r.Context(), view, name)...)
+		}
+		if err := h.client().RemoveLocalData(r.Context(), view, name); err != nil {
+			respondWithClientError(w, r, err)
+			return
+		}
+	}
+	audit.RecordState(r.Context(), before, nil)
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Local data removed successfully",
+	})
+}
+
+// sameName compares domain names ignoring case and the trailing dot
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// localZonesNamed returns the local zones called name, recorded in the audit
+// log before a removal. Errors only mean the state is not recorded.
+func (h *UnboundHandler) localZonesNamed(ctx context.Context, view, name string) []response.LocalZone {
+	zones, _ := h.client().ListLocalZones(ctx, view)
+	var named []response.LocalZone
+	for _, zone := range zones {
+		if sameName(zone.Name, name) {
+			named = append(named, zone)
+		}
+	}
+	return named
+}
+
+// localDataNamed returns the local data for name, recorded in the audit log
+// before a removal. Errors only mean the state is not recorded.
+func (h *UnboundHandler) localDataNamed(ctx context.Context, view, name string) []response.LocalData {
+	records, _ := h.client().ListLocalData(ctx, view)
+	var named []response.LocalData
+	for _, record := range records {
+		if sameName(record.Name, name) {
+			named = append(named, record)
+		}
+	}
+	return named
+}
+
+func (h *UnboundHandler) ListViews(w http.ResponseWriter, r *http.Request) {
+	views, err := unbound.ListViews(h.configFile)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    views,
+	})
+}
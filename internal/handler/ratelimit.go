@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// allParam reports whether the "all" query parameter is set, which maps to
+// the +a option of the rate limit list commands
+func allParam(r *http.Request) bool {
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	return all
+}
+
+func (h *UnboundHandler) RateLimits(w http.ResponseWriter, r *http.Request) {
+	all := allParam(r)
+
+	domains, err := h.client().RateLimitList(r.Context(), all)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	ips, err := h.client().IPRateLimitList(r.Context(), all)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	// stats_noreset, since plain stats would reset the counters read by
+	// /stats and the metrics endpoint
+	stats, err := h.client().StatsNoReset(r.Context())
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data: response.RateLimitResponse{
+			Domains:       domains,
+			IPs:           ips,
+			IPRateLimited: stats.Queries.IPRateLimited,
+		},
+	})
+}
+
+func (h *UnboundHandler) DomainRateLimits(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.client().RateLimitList(r.Context(), allParam(r))
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    domains,
+	})
+}
+
+func (h *UnboundHandler) IPRateLimits(w http.ResponseWriter, r *http.Request) {
+	ips, err := h.client().IPRateLimitList(r.Context(), allParam(r))
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    ips,
+	})
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// RequestList returns the in-flight queries. The optional "sort=age" parameter
+// orders them oldest first and "min_age" drops entries younger than the given
+// number of seconds, which makes stuck upstream queries easy to spot.
+func (h *UnboundHandler) RequestList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var minAge float64
+	if v := query.Get("min_age"); v != "" {
+		age, err := strconv.ParseFloat(v, 64)
+		if err != nil || age < 0 {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid min_age")
+			return
+		}
+		minAge = age
+	}
+
+	sortBy := query.Get("sort")
+	if sortBy != "" && sortBy != "age" {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid sort, supported values: age")
+		return
+	}
+
+	entries, err := h.client().RequestList(r.Context())
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if entry.Age >= minAge {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	if sortBy == "age" {
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return filtered[i].Age > filtered[j].Age
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    filtered,
+	})
+}
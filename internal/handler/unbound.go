@@ -2,26 +2,37 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
 	"github.com/callMe-Root/unbound-control-api/internal/response"
 	"github.com/callMe-Root/unbound-control-api/internal/unbound"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
 )
 
 type UnboundHandler struct {
-	client *unbound.Client
+	client     func() *unbound.Client
+	configFile string
+	stop       stopGuard
 }
 
-func NewUnboundHandler(client *unbound.Client) *UnboundHandler {
+// NewUnboundHandler creates a handler for the Unbound control routes.
+// client returns the current Unbound client, which changes when the control
+// socket is reconfigured. configFile is the unbound.conf used to enumerate
+// views and may be empty.
+func NewUnboundHandler(client func() *unbound.Client, configFile string) *UnboundHandler {
 	return &UnboundHandler{
-		client: client,
+		client:     client,
+		configFile: configFile,
 	}
 }
 
 func (h *UnboundHandler) Status(w http.ResponseWriter, r *http.Request) {
-	status, err := h.client.Status()
+	status, err := h.client().Status(r.Context())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithClientError(w, r, err)
 		return
 	}
 
@@ -31,29 +42,77 @@ func (h *UnboundHandler) Status(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Reload reloads the Unbound configuration. keep_cache=true uses
+// reload_keep_cache to avoid a cold cache, and verify=true waits until
+// Unbound reports back with a reset uptime before responding.
 func (h *UnboundHandler) Reload(w http.ResponseWriter, r *http.Request) {
-	err := h.client.Reload()
+	keepCache, ok := boolParam(r, "keep_cache")
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid keep_cache value")
+		return
+	}
+	verify, ok := boolParam(r, "verify")
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid verify value")
+		return
+	}
+
+	// The status before the reload is only used as a baseline, so a failure
+	// here must not prevent the reload itself
+	client := h.client()
+	var before *response.StatusResponse
+	if verify {
+		before, _ = client.Status(r.Context())
+	}
+
+	reloadedAt := time.Now()
+	var err error
+	if keepCache {
+		err = client.ReloadKeepCache(r.Context())
+	} else {
+		err = client.Reload(r.Context())
+	}
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	if !verify {
+		respondWithJSON(w, http.StatusOK, response.CommonResponse{
+			Success: true,
+			Data:    "Configuration reloaded successfully",
+		})
+		return
+	}
+
+	after, err := client.WaitForReload(r.Context(), before, reloadedAt, reloadVerifyTimeout)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, r, http.StatusGatewayTimeout, err.Error())
 		return
 	}
+	audit.RecordState(r.Context(), before, after)
 
 	respondWithJSON(w, http.StatusOK, response.CommonResponse{
 		Success: true,
-		Data:    "Configuration reloaded successfully",
+		Data: response.ReloadResponse{
+			Message:   "Configuration reloaded successfully",
+			KeepCache: keepCache,
+			Version:   after.Version,
+			Uptime:    after.Uptime,
+		},
 	})
 }
 
 func (h *UnboundHandler) Flush(w http.ResponseWriter, r *http.Request) {
 	domain := r.URL.Query().Get("domain")
 	if domain == "" {
-		respondWithError(w, http.StatusBadRequest, "Domain is required")
+		respondWithError(w, r, http.StatusBadRequest, "Domain is required")
 		return
 	}
 
-	err := h.client.Flush(domain)
+	err := h.client().Flush(r.Context(), domain)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithClientError(w, r, err)
 		return
 	}
 
@@ -63,10 +122,64 @@ func (h *UnboundHandler) Flush(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// FlushZone removes a domain and everything below it from the cache
+func (h *UnboundHandler) FlushZone(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		respondWithError(w, r, http.StatusBadRequest, "Domain is required")
+		return
+	}
+
+	err := h.client().FlushZone(r.Context(), domain)
+	if err != nil {
+		respondWithClientError(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response.CommonResponse{
+		Success: true,
+		Data:    "Zone flushed successfully",
+	})
+}
+
+// Health reports whether the control socket is reachable and the state of the
+// client's circuit breaker
+func (h *UnboundHandler) Health(w http.ResponseWriter, r *http.Request) {
+	client := h.client()
+	health := response.HealthResponse{
+		SocketReachable: client.VerifyConnection(),
+		Breaker:         client.BreakerStatus(),
+	}
+
+	code := http.StatusOK
+	if !health.SocketReachable || health.Breaker.State == unbound.BreakerOpen {
+		code = http.StatusServiceUnavailable
+	}
+
+	respondWithJSON(w, code, response.CommonResponse{
+		Success: code == http.StatusOK,
+		Data:    health,
+	})
+}
+
+// Stats returns Unbound's statistics. They are read with stats_noreset unless
+// reset=true asks Unbound to reset its counters afterwards.
 func (h *UnboundHandler) Stats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.client.Stats()
+	reset, ok := boolParam(r, "reset")
+	if !ok {
+		respondWithError(w, r, http.StatusBadRequest, "Invalid reset value")
+		return
+	}
+
+	var stats *response.StatsResponse
+	var err error
+	if reset {
+		stats, err = h.client().Stats(r.Context())
+	} else {
+		stats, err = h.client().StatsNoReset(r.Context())
+	}
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithClientError(w, r, err)
 		return
 	}
 
@@ -76,16 +189,51 @@ func (h *UnboundHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, response.CommonResponse{
+func respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	respondWithErrorCode(w, r, code, errorCode(code), message)
+}
+
+// respondWithErrorCode writes an error response with an explicit error code,
+// tagged with the request ID so it can be matched to the server logs
+func respondWithErrorCode(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	respondWithJSON(w, status, response.CommonResponse{
 		Success: false,
 		Error: &response.Error{
-			Code:    "INTERNAL_ERROR",
-			Message: message,
+			Code:      code,
+			Message:   message,
+			RequestID: logger.RequestID(r.Context()),
 		},
 	})
 }
 
+// respondWithClientError reports an error returned by the Unbound client,
+// answering 503 when Unbound could not be reached at all
+func respondWithClientError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, unbound.ErrUnreachable) {
+		respondWithErrorCode(w, r, http.StatusServiceUnavailable, "UNBOUND_UNREACHABLE", err.Error())
+		return
+	}
+	respondWithError(w, r, http.StatusInternalServerError, err.Error())
+}
+
+// errorCode maps an HTTP status to the error code reported in the response body
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "BAD_REQUEST"
+	case http.StatusForbidden:
+		return "FORBIDDEN"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "CONFLICT"
+	case http.StatusGatewayTimeout:
+		return "TIMEOUT"
+	default:
+		return "INTERNAL_ERROR"
+	}
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload)
 
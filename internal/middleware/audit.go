@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// auditMaxBody bounds the request body recorded as parameters
+	auditMaxBody = 16 * 1024
+
+	// auditMaxError bounds the error response captured for failed requests
+	auditMaxError = 4 * 1024
+)
+
+// auditRecorder captures the status code, and the body of error responses
+type auditRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	errorBody  bytes.Buffer
+}
+
+func (ar *auditRecorder) WriteHeader(code int) {
+	ar.statusCode = code
+	ar.ResponseWriter.WriteHeader(code)
+}
+
+func (ar *auditRecorder) Write(b []byte) (int, error) {
+	if room := auditMaxError - ar.errorBody.Len(); ar.statusCode >= http.StatusBadRequest && room > 0 {
+		if len(b) > room {
+			ar.errorBody.Write(b[:room])
+		} else {
+			ar.errorBody.Write(b)
+		}
+	}
+	return ar.ResponseWriter.Write(b)
+}
+
+// isStateChanging reports whether requests with method may change state
+func isStateChanging(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// Audit middleware writes a record for every state-changing request to the
+// audit log once it has been handled. It must run after authentication so
+// the caller's identity is known. Parameters are redacted with rules.
+func Audit(log *audit.Log, rules RedactionRules) func(http.Handler) http.Handler {
+	rd := newRedactor(rules)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isStateChanging(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Capture the start of the body for the record and restore it
+			var body []byte
+			if r.Body != nil {
+				body, _ = io.ReadAll(io.LimitReader(r.Body, auditMaxBody+1))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			}
+
+			ctx, entry := audit.NewContext(r.Context())
+			rec := &auditRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			record := audit.Record{
+				Time:      start.UTC(),
+				SourceIP:  getClientIP(r),
+				RequestID: logger.RequestID(r.Context()),
+				Method:    r.Method,
+				Route:     r.URL.Path,
+				Path:      r.URL.Path,
+				Params:    rd.auditParams(r, body),
+				Status:    rec.statusCode,
+				Outcome:   audit.OutcomeSuccess,
+			}
+			if identity := auth.FromContext(r.Context()); identity != nil {
+				record.Identity = identity.Name
+				record.AuthMethod = identity.Method
+			}
+			if current := mux.CurrentRoute(r); current != nil {
+				if tmpl, err := current.GetPathTemplate(); err == nil {
+					record.Route = tmpl
+				}
+			}
+			if rec.statusCode >= http.StatusBadRequest {
+				record.Outcome = audit.OutcomeFailure
+				record.Error = errorMessage(rec.errorBody.Bytes())
+			}
+			entry.Fill(&record)
+
+			if err := log.Append(record); err != nil {
+				logger.Get().Error().
+					Err(err).
+					Str("request_id", record.RequestID).
+					Msg("failed to write audit record")
+			}
+		})
+	}
+}
+
+// auditParams collects the query parameters and the JSON body of a request,
+// with secrets redacted. Bodies that are not JSON or too large to parse are
+// not recorded.
+func (rd *redactor) auditParams(r *http.Request, body []byte) map[string]interface{} {
+	params := make(map[string]interface{})
+	for name, values := range r.URL.Query() {
+		if rd.anywhere[name] {
+			params[name] = redacted
+			continue
+		}
+		params[name] = strings.Join(values, ",")
+	}
+
+	switch {
+	case len(body) > auditMaxBody:
+		params["body_truncated"] = true
+	case len(bytes.TrimSpace(body)) > 0:
+		if doc, ok := rd.redactJSON(body); ok {
+			params["body"] = json.RawMessage(doc)
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// errorMessage extracts the message from an error response, which is either
+// the JSON error body of a handler or plain text from http.Error
+func errorMessage(body []byte) string {
+	var resp response.CommonResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != nil {
+		return resp.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -1,33 +1,122 @@
 package middleware
 
 import (
-	"crypto/subtle"
 	"net/http"
+	"strings"
+
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/metrics"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+	"github.com/callMe-Root/unbound-control-api/pkg/signing"
 )
 
 const (
 	// AuthHeaderKey is the header key for API key authentication
 	AuthHeaderKey = "X-API-Key"
+
+	// bearerPrefix starts an Authorization header carrying a bearer token
+	bearerPrefix = "Bearer "
 )
 
-// APIKeyAuth middleware checks for a valid API key in the request header
-func APIKeyAuth(validAPIKey string) func(http.Handler) http.Handler {
+// Authenticators are the ways a caller can authenticate. Keys is required,
+// the others are nil when the method is disabled.
+type Authenticators struct {
+	Keys        *auth.KeySet
+	JWT         *auth.JWTVerifier
+	Signatures  *auth.SignatureVerifier
+	ClientCerts *auth.CertMapper
+}
+
+// Authenticate middleware checks the request for an API key or, if enabled,
+// a bearer token, request signature or verified client certificate, and
+// stores the caller's
+// identity in the request context. Credentials sent in headers take
+// precedence over the client certificate, in the order listed.
+func Authenticate(a Authenticators) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get API key from header
-			apiKey := r.Header.Get(AuthHeaderKey)
-			if apiKey == "" {
-				http.Error(w, "Unauthorized - Missing API key", http.StatusUnauthorized)
+			if apiKey := r.Header.Get(AuthHeaderKey); apiKey != "" {
+				identity, ok := a.Keys.Lookup(apiKey)
+				if !ok {
+					metrics.AuthFailures.WithLabelValues("invalid_key").Inc()
+					respondWithError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid API key")
+					return
+				}
+				authorize(w, r, next, identity)
+				return
+			}
+
+			header := r.Header.Get("Authorization")
+			if a.JWT != nil && strings.HasPrefix(header, bearerPrefix) {
+				identity, err := a.JWT.Verify(r.Context(), strings.TrimPrefix(header, bearerPrefix))
+				if err != nil {
+					metrics.AuthFailures.WithLabelValues("invalid_token").Inc()
+					logger.Get().Debug().
+						Str("request_id", logger.RequestID(r.Context())).
+						Err(err).
+						Msg("Rejected bearer token")
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					respondWithError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid bearer token")
+					return
+				}
+				authorize(w, r, next, identity)
+				return
+			}
+
+			if a.Signatures != nil && r.Header.Get(signing.HeaderSignature) != "" {
+				identity, err := a.Signatures.Verify(r)
+				if err != nil {
+					metrics.AuthFailures.WithLabelValues("invalid_signature").Inc()
+					logger.Get().Debug().
+						Str("request_id", logger.RequestID(r.Context())).
+						Err(err).
+						Msg("Rejected request signature")
+					respondWithError(w, r, http.StatusUnauthorized, codeUnauthorized, "Invalid request signature")
+					return
+				}
+				authorize(w, r, next, identity)
 				return
 			}
 
-			// Use constant time comparison to prevent timing attacks
-			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(validAPIKey)) != 1 {
-				http.Error(w, "Unauthorized - Invalid API key", http.StatusUnauthorized)
+			if a.ClientCerts != nil && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				identity, ok := a.ClientCerts.Identify(r.TLS.VerifiedChains[0][0])
+				if !ok {
+					metrics.AuthFailures.WithLabelValues("unknown_cert").Inc()
+					respondWithError(w, r, http.StatusUnauthorized, codeUnauthorized, "Client certificate is not mapped to an identity")
+					return
+				}
+				authorize(w, r, next, identity)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			metrics.AuthFailures.WithLabelValues("missing_key").Inc()
+			if a.JWT != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			respondWithError(w, r, http.StatusUnauthorized, codeUnauthorized, "Missing API key")
 		})
 	}
 }
+
+// authorize continues with the authenticated identity, unless the identity
+// is restricted to addresses the request does not come from
+func authorize(w http.ResponseWriter, r *http.Request, next http.Handler, identity *auth.Identity) {
+	if !identity.Sources.Allows(clientAddr(r)) {
+		metrics.AuthFailures.WithLabelValues("source_not_allowed").Inc()
+		respondWithError(w, r, http.StatusForbidden, codeForbidden, "Source address not allowed for this identity")
+		return
+	}
+	next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), identity)))
+}
+
+// RequireScope wraps a handler so it is only reachable by callers granted scope
+func RequireScope(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.HasScope(r.Context(), scope) {
+			metrics.AuthFailures.WithLabelValues("insufficient_scope").Inc()
+			respondWithError(w, r, http.StatusForbidden, codeForbidden, "Requires scope "+scope)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"github.com/callMe-Root/unbound-control-api/internal/auth"
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/metrics"
+)
+
+// clientIPKey is the context key for the resolved client address
+type clientIPKey struct{}
+
+// SourcePolicy decides which address a request comes from and whether that
+// address may use the API. Forwarding headers are only honoured when the
+// connection comes from a trusted proxy. It can be updated at runtime when
+// the configuration is reloaded.
+type SourcePolicy struct {
+	mu      sync.RWMutex
+	proxies []netip.Prefix
+	global  *auth.IPPolicy
+}
+
+// NewSourcePolicy builds a source policy from the security configuration
+func NewSourcePolicy(cfg config.SecurityConfig) (*SourcePolicy, error) {
+	p := &SourcePolicy{}
+	if err := p.Update(cfg); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update replaces the trusted proxies and global address lists
+func (p *SourcePolicy) Update(cfg config.SecurityConfig) error {
+	apply, err := p.Prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare parses the proxies and address lists in cfg and returns a function
+// that puts them in place
+func (p *SourcePolicy) Prepare(cfg config.SecurityConfig) (func(), error) {
+	proxies, err := auth.ParsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	global, err := auth.NewIPPolicy(cfg.AllowCIDRs, cfg.DenyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		p.mu.Lock()
+		p.proxies = proxies
+		p.global = global
+		p.mu.Unlock()
+	}, nil
+}
+
+// resolve returns the client address of r. With a trusted peer, the
+// X-Forwarded-For chain is walked from the right and the first address that
+// is not itself a trusted proxy is the client; X-Real-IP is used when there
+// is no X-Forwarded-For. Headers from untrusted peers are ignored.
+func (p *SourcePolicy) resolve(r *http.Request) netip.Addr {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	addr := remoteAddr(r)
+	if !addr.IsValid() || !auth.ContainsAddr(p.proxies, addr) {
+		return addr
+	}
+
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = hop.Unmap()
+			if !auth.ContainsAddr(p.proxies, addr) {
+				break
+			}
+		}
+		return addr
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap()
+	}
+	return addr
+}
+
+// allows reports whether addr passes the global allow and deny lists
+func (p *SourcePolicy) allows(addr netip.Addr) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.global.Allows(addr)
+}
+
+// ClientIP middleware resolves the client address once and stores it in the
+// request context for logging, rate limiting and address checks
+func ClientIP(p *SourcePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, p.resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// SourceFilter middleware rejects requests from addresses outside the
+// global allow list or on the deny list, before any authentication
+func SourceFilter(p *SourcePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.allows(clientAddr(r)) {
+				metrics.AuthFailures.WithLabelValues("source_denied").Inc()
+				respondWithError(w, r, http.StatusForbidden, codeForbidden, "Source address not allowed")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientAddr returns the client address resolved by ClientIP, or the peer
+// address if the middleware did not run
+func clientAddr(r *http.Request) netip.Addr {
+	if addr, ok := r.Context().Value(clientIPKey{}).(netip.Addr); ok {
+		return addr
+	}
+	return remoteAddr(r)
+}
+
+// getClientIP returns the client address of the request as a string
+func getClientIP(r *http.Request) string {
+	if addr := clientAddr(r); addr.IsValid() {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+// remoteAddr parses the address of the connection's peer
+func remoteAddr(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
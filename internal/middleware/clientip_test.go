@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+func TestSourcePolicyResolve(t *testing.T) {
+	p, err := NewSourcePolicy(config.SecurityConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		DenyCIDRs:      []string{"198.51.100.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("NewSourcePolicy: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		peer   string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"direct", "192.0.2.1:1234", nil, "", "192.0.2.1"},
+		{"spoofed XFF from untrusted peer", "198.51.100.7:1234", []string{"192.0.2.9"}, "", "198.51.100.7"},
+		{"spoofed X-Real-IP from untrusted peer", "198.51.100.7:1234", nil, "192.0.2.9", "198.51.100.7"},
+		{"XFF from trusted proxy", "10.0.0.1:1234", []string{"192.0.2.9"}, "", "192.0.2.9"},
+		{"spoofed left hop behind trusted proxy", "10.0.0.1:1234", []string{"192.0.2.66, 198.51.100.7"}, "", "198.51.100.7"},
+		{"chain of trusted proxies", "10.0.0.1:1234", []string{"192.0.2.9, 10.0.0.2", "10.0.0.3"}, "", "192.0.2.9"},
+		{"garbage hop stops the walk", "10.0.0.1:1234", []string{"192.0.2.9, bogus, 10.0.0.2"}, "", "10.0.0.2"},
+		{"X-Real-IP from trusted proxy", "10.0.0.1:1234", nil, "192.0.2.9", "192.0.2.9"},
+		{"IPv4-mapped hop", "10.0.0.1:1234", []string{"::ffff:192.0.2.9"}, "", "192.0.2.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			got := p.resolve(r)
+			if got.String() != tt.want {
+				t.Errorf("resolve = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceFilterIgnoresSpoofedXFF(t *testing.T) {
+	p, err := NewSourcePolicy(config.SecurityConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		DenyCIDRs:      []string{"198.51.100.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("NewSourcePolicy: %v", err)
+	}
+	handler := RequestID()(ClientIP(p)(SourceFilter(p)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))))
+
+	// A denied client claiming to be an allowed address
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "198.51.100.7:1234"
+	r.Header.Set("X-Forwarded-For", "192.0.2.9")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var body response.CommonResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("body %q is not a JSON error: %v", rec.Body, err)
+	}
+	if body.Error.Code != codeForbidden || body.Error.RequestID == "" || body.Error.RequestID != rec.Header().Get(RequestIDHeader) {
+		t.Errorf("error = %+v, want %s with request ID %q", body.Error, codeForbidden, rec.Header().Get(RequestIDHeader))
+	}
+}
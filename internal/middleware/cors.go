@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/pkg/signing"
+)
+
+// Defaults for unset CORS settings
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	defaultCORSHeaders = []string{
+		"Content-Type", "Authorization", AuthHeaderKey, RequestIDHeader,
+		signing.HeaderKeyID, signing.HeaderTimestamp, signing.HeaderNonce, signing.HeaderSignature,
+	}
+	defaultCORSExposed = []string{RequestIDHeader, "WWW-Authenticate"}
+)
+
+// defaultCORSMaxAge is how long browsers may cache a preflight response
+const defaultCORSMaxAge = 10 * time.Minute
+
+// CORSPolicy decides which browser origins may call the API. It can be
+// updated at runtime when the configuration is reloaded.
+type CORSPolicy struct {
+	mu    sync.RWMutex
+	rules *corsRules
+}
+
+// corsRules is one version of the policy, never modified once built
+type corsRules struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	methods     []string
+	headers     string
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// NewCORSPolicy builds a CORS policy from the configuration
+func NewCORSPolicy(cfg config.CORSConfig) (*CORSPolicy, error) {
+	p := &CORSPolicy{}
+	if err := p.Update(cfg); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Update replaces the policy with cfg. Credentials cannot be combined with
+// the "*" origin, so a browser never sends keys to an arbitrary site's page.
+func (p *CORSPolicy) Update(cfg config.CORSConfig) error {
+	apply, err := p.Prepare(cfg)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// Prepare checks cfg and returns a function that puts the policy in place
+func (p *CORSPolicy) Prepare(cfg config.CORSConfig) (func(), error) {
+	if len(cfg.AllowedOrigins) == 0 {
+		return nil, errors.New("cors needs at least one allowed origin")
+	}
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+	if anyOrigin && cfg.AllowCredentials {
+		return nil, errors.New(`cors cannot allow credentials for the "*" origin`)
+	}
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	upper := make([]string, len(methods))
+	for i, m := range methods {
+		upper[i] = strings.ToUpper(m)
+	}
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	exposed := cfg.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = defaultCORSExposed
+	}
+	maxAge := cfg.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	rules := &corsRules{
+		anyOrigin:   anyOrigin,
+		origins:     origins,
+		methods:     upper,
+		headers:     strings.Join(headers, ", "),
+		exposed:     strings.Join(exposed, ", "),
+		credentials: cfg.AllowCredentials,
+		maxAge:      strconv.Itoa(int(maxAge.Seconds())),
+	}
+
+	return func() {
+		p.mu.Lock()
+		p.rules = rules
+		p.mu.Unlock()
+	}, nil
+}
+
+// current returns the rules in effect
+func (p *CORSPolicy) current() *corsRules {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.rules
+}
+
+// CORS middleware answers preflight requests from allowed origins and adds
+// the CORS headers to their other requests. Requests from other origins get
+// no CORS headers, so browsers refuse to hand the response to the page.
+// Preflight requests are answered here, before authentication, as browsers
+// never send credentials with them.
+func CORS(p *CORSPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+
+			rules := p.current()
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !rules.anyOrigin && !rules.origins[origin] {
+				if preflight {
+					respondWithError(w, r, http.StatusForbidden, codeForbidden, "Origin not allowed")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			if rules.anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if rules.credentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				if !slices.Contains(rules.methods, r.Header.Get("Access-Control-Request-Method")) {
+					respondWithError(w, r, http.StatusForbidden, codeForbidden, "Method not allowed")
+					return
+				}
+				h.Set("Access-Control-Allow-Methods", strings.Join(rules.methods, ", "))
+				h.Set("Access-Control-Allow-Headers", rules.headers)
+				h.Set("Access-Control-Max-Age", rules.maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			h.Set("Access-Control-Expose-Headers", rules.exposed)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+)
+
+// Error codes of requests rejected before reaching a handler
+const (
+	codeUnauthorized = "UNAUTHORIZED"
+	codeForbidden    = "FORBIDDEN"
+	codeRateLimited  = "RATE_LIMITED"
+)
+
+// respondWithError writes the same JSON error body as the handlers, tagged
+// with the request ID so the rejection can be matched to the server logs
+func respondWithError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	body, _ := json.Marshal(response.CommonResponse{
+		Success: false,
+		Error: &response.Error{
+			Code:      code,
+			Message:   message,
+			RequestID: logger.RequestID(r.Context()),
+		},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -8,13 +8,17 @@ import (
 
 	"github.com/callMe-Root/unbound-control-api/pkg/logger"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// responseWriter is a custom response writer that captures the status code and body
+// responseWriter is a custom response writer that captures the status code
+// and, when body is set, up to limit bytes of the body
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
 	body       *bytes.Buffer
+	limit      int
+	truncated  bool
 }
 
 // WriteHeader captures the status code
@@ -25,34 +29,48 @@ func (rw *responseWriter) WriteHeader(code int) {
 
 // Write captures the response body
 func (rw *responseWriter) Write(b []byte) (int, error) {
-	if rw.body == nil {
-		rw.body = bytes.NewBuffer(b)
-	} else {
-		rw.body.Write(b)
+	if rw.body != nil {
+		room := rw.limit - rw.body.Len()
+		if len(b) > room {
+			rw.truncated = true
+			rw.body.Write(b[:max(room, 0)])
+		} else {
+			rw.body.Write(b)
+		}
 	}
 	return rw.ResponseWriter.Write(b)
 }
 
-// LoggingMiddleware logs information about each request
-func LoggingMiddleware() func(http.Handler) http.Handler {
+// LoggingMiddleware logs information about each request. In debug mode it
+// also logs headers and bodies, subject to rules.
+func LoggingMiddleware(rules RedactionRules) func(http.Handler) http.Handler {
+	rd := newRedactor(rules)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			debug := zerolog.GlobalLevel() <= zerolog.DebugLevel
 
 			// Create a custom response writer to capture the status code and body
 			rw := &responseWriter{
 				ResponseWriter: w,
 				statusCode:     http.StatusOK,
-				body:           bytes.NewBuffer(nil),
+				limit:          rd.maxBodyBytes,
 			}
 
-			// Capture request body if debug level is enabled
+			// Capture the start of the request body if debug level is enabled
 			var requestBody []byte
-			if zerolog.GlobalLevel() == zerolog.DebugLevel {
+			var requestTruncated bool
+			if debug {
+				rw.body = bytes.NewBuffer(nil)
 				if r.Body != nil {
-					requestBody, _ = io.ReadAll(r.Body)
+					requestBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(rd.maxBodyBytes)+1))
 					// Restore the request body for the handler
-					r.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+					r.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+					if len(requestBody) > rd.maxBodyBytes {
+						requestBody = requestBody[:rd.maxBodyBytes]
+						requestTruncated = true
+					}
 				}
 			}
 
@@ -73,6 +91,7 @@ func LoggingMiddleware() func(http.Handler) http.Handler {
 
 			// Log request details
 			event.
+				Str("request_id", logger.RequestID(r.Context())).
 				Str("method", r.Method).
 				Str("path", r.URL.Path).
 				Str("ip", getClientIP(r)).
@@ -80,17 +99,55 @@ func LoggingMiddleware() func(http.Handler) http.Handler {
 				Dur("duration", duration).
 				Str("user_agent", r.UserAgent())
 
-			// Add request/response bodies in debug mode
-			if zerolog.GlobalLevel() == zerolog.DebugLevel {
-				if len(requestBody) > 0 {
-					event.RawJSON("request_body", requestBody)
-				}
-				if rw.body.Len() > 0 {
-					event.RawJSON("response_body", rw.body.Bytes())
-				}
+			// Correlate with the trace when tracing is enabled
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				event.Str("trace_id", sc.TraceID().String())
+			}
+
+			// Add headers and request/response bodies in debug mode
+			if debug {
+				event.Interface("request_headers", rd.redactHeaders(r.Header))
+				rd.addBody(event, "request_body", r.Header.Get("Content-Type"), requestBody, requestTruncated)
+				rd.addBody(event, "response_body", rw.Header().Get("Content-Type"), rw.body.Bytes(), rw.truncated)
 			}
 
 			event.Msg("request completed")
 		})
 	}
 }
+
+// addBody adds a captured body to event. JSON bodies are logged as JSON with
+// secret fields redacted, other bodies as a string. Bodies that look like JSON
+// are treated as JSON whatever their Content-Type, so that secrets sent
+// without the right header are still redacted.
+func (rd *redactor) addBody(event *zerolog.Event, key, contentType string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+
+	if truncated {
+		event.Bool(key+"_truncated", true)
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	looksJSON := len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+	if !isJSON(contentType) && !looksJSON {
+		event.Str(key, string(body))
+		return
+	}
+
+	// A truncated document cannot be parsed, so secrets in it cannot be found
+	if !truncated {
+		if redactedBody, ok := rd.redactJSON(body); ok {
+			event.RawJSON(key, redactedBody)
+			return
+		}
+	}
+	event.Str(key, redacted)
+}
+
+// readCloser pairs a reader with the closer of the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -2,12 +2,15 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 	"sync"
 	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/metrics"
 )
 
-// RateLimiter implements a token bucket rate limiter
+// RateLimiter implements a token bucket rate limiter. Its rate and bucket
+// size can be updated at runtime when the configuration is reloaded.
 type RateLimiter struct {
 	rate       float64 // tokens per second
 	bucketSize float64 // maximum bucket size
@@ -20,37 +23,32 @@ type clientLimiter struct {
 	lastRefill time.Time
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(rate, bucketSize float64) *RateLimiter {
-	return &RateLimiter{
-		rate:       rate,
-		bucketSize: bucketSize,
+// NewRateLimiter creates a rate limiter from the configuration and starts
+// the goroutine that forgets idle clients
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		rate:       cfg.RequestsPerSecond,
+		bucketSize: cfg.BurstSize,
 		clients:    make(map[string]*clientLimiter),
 	}
-}
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Try X-Forwarded-For header first
-	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
-		// Get the first IP in the chain
-		ips := strings.Split(forwardedFor, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
+	go func() {
+		for {
+			time.Sleep(time.Hour)
+			rl.cleanup()
 		}
-	}
+	}()
+	return rl
+}
 
-	// Try X-Real-IP header
-	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-		return realIP
-	}
+// Update replaces the rate and bucket size. Clients keep their tokens, capped
+// to the new bucket size on their next request.
+func (rl *RateLimiter) Update(cfg config.RateLimitConfig) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	// Fall back to remote address
-	ip := r.RemoteAddr
-	if strings.Contains(ip, ":") {
-		ip = strings.Split(ip, ":")[0]
-	}
-	return ip
+	rl.rate = cfg.RequestsPerSecond
+	rl.bucketSize = cfg.BurstSize
 }
 
 // refill adds tokens to the bucket based on elapsed time
@@ -99,22 +97,13 @@ func (rl *RateLimiter) cleanup() {
 }
 
 // RateLimit middleware limits the number of requests per second per client
-func RateLimit(requestsPerSecond, burstSize float64) func(http.Handler) http.Handler {
-	limiter := NewRateLimiter(requestsPerSecond, burstSize)
-
-	// Start cleanup goroutine
-	go func() {
-		for {
-			time.Sleep(time.Hour)
-			limiter.cleanup()
-		}
-	}()
-
+func RateLimit(limiter *RateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ip := getClientIP(r)
 			if !limiter.allow(ip) {
-				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				metrics.RateLimitRejections.Inc()
+				respondWithError(w, r, http.StatusTooManyRequests, codeRateLimited, "Too many requests")
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// redacted replaces secret values in logs
+	redacted = "[REDACTED]"
+
+	// DefaultMaxBodyBytes is used when RedactionRules.MaxBodyBytes is not set
+	DefaultMaxBodyBytes = 4096
+)
+
+// alwaysRedactedHeaders are never logged regardless of configuration
+var alwaysRedactedHeaders = []string{AuthHeaderKey, "Authorization", "Cookie", "Set-Cookie"}
+
+// alwaysRedactedFields are JSON fields never logged regardless of configuration
+var alwaysRedactedFields = []string{"secret", "api_key", "password", "token"}
+
+// RedactionRules controls what the debug request/response logging may show.
+// Field paths are dot separated, "*" matches any key or array element, and a
+// path without dots matches the field at any depth.
+type RedactionRules struct {
+	Headers      []string
+	Fields       []string
+	MaxBodyBytes int
+}
+
+// redactor applies RedactionRules to headers and bodies
+type redactor struct {
+	headers      map[string]bool
+	anywhere     map[string]bool
+	paths        [][]string
+	maxBodyBytes int
+}
+
+func newRedactor(rules RedactionRules) *redactor {
+	rd := &redactor{
+		headers:      make(map[string]bool),
+		anywhere:     make(map[string]bool),
+		maxBodyBytes: rules.MaxBodyBytes,
+	}
+	if rd.maxBodyBytes <= 0 {
+		rd.maxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	for _, h := range append(alwaysRedactedHeaders, rules.Headers...) {
+		rd.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	for _, f := range append(alwaysRedactedFields, rules.Fields...) {
+		if strings.Contains(f, ".") {
+			rd.paths = append(rd.paths, strings.Split(f, "."))
+		} else {
+			rd.anywhere[f] = true
+		}
+	}
+
+	return rd
+}
+
+// redactHeaders returns the headers with secret values replaced
+func (rd *redactor) redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if rd.headers[http.CanonicalHeaderKey(name)] {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// redactJSON returns body with secret fields replaced, or false if body is
+// not valid JSON
+func (rd *redactor) redactJSON(body []byte) ([]byte, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, false
+	}
+
+	doc = rd.redactAnywhere(doc)
+	for _, path := range rd.paths {
+		doc = redactPath(doc, path)
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// redactAnywhere replaces fields matching a dotless rule at any depth
+func (rd *redactor) redactAnywhere(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if rd.anywhere[key] {
+				node[key] = redacted
+				continue
+			}
+			node[key] = rd.redactAnywhere(child)
+		}
+	case []interface{}:
+		for i, child := range node {
+			node[i] = rd.redactAnywhere(child)
+		}
+	}
+	return v
+}
+
+// redactPath replaces the value at path, expanding "*" segments
+func redactPath(v interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return redacted
+	}
+
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if path[0] == "*" || path[0] == key {
+				node[key] = redactPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range node {
+				node[i] = redactPath(child, path[1:])
+			}
+		}
+	}
+	return v
+}
+
+// isJSON reports whether a Content-Type header denotes JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
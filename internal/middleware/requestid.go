@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+)
+
+const (
+	// RequestIDHeader is the header used to accept and return the request ID
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds client supplied request IDs
+	maxRequestIDLength = 128
+)
+
+// validRequestID reports whether a client supplied ID is safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// RequestID middleware assigns every request an ID, reusing a valid
+// X-Request-ID from the client, stores it in the request context for logging
+// and returns it in the response header
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+		})
+	}
+}
@@ -75,9 +75,9 @@ func ParseStatsResponse(raw string) (*StatsResponse, error) {
 
 		// Map the key to the appropriate field
 		switch {
-		case strings.HasPrefix(key, "total.num.queries"):
+		case key == "total.num.queries":
 			stats.Queries.Total = int(val)
-		case strings.HasPrefix(key, "total.num.queries_ip_ratelimited"):
+		case key == "total.num.queries_ip_ratelimited":
 			stats.Queries.IPRateLimited = int(val)
 		case strings.HasPrefix(key, "total.num.cachehits"):
 			stats.Cache.Hits = int(val)
@@ -113,6 +113,158 @@ func ParseStatsResponse(raw string) (*StatsResponse, error) {
 	return stats, nil
 }
 
+// ParseLocalZones parses the raw list_local_zones response into LocalZone entries
+func ParseLocalZones(raw string) ([]LocalZone, error) {
+	zones := []LocalZone{}
+
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		zones = append(zones, LocalZone{
+			Name: fields[0],
+			Type: fields[1],
+		})
+	}
+
+	return zones, nil
+}
+
+// ParseLocalData parses the raw list_local_data response into LocalData records
+func ParseLocalData(raw string) ([]LocalData, error) {
+	records := []LocalData{}
+
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		// Records are printed as "name ttl class type rdata..."
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		ttl, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+
+		records = append(records, LocalData{
+			Name:  fields[0],
+			TTL:   ttl,
+			Class: fields[2],
+			Type:  fields[3],
+			RData: strings.Join(fields[4:], " "),
+		})
+	}
+
+	return records, nil
+}
+
+// ParseRateLimitList parses the raw ratelimit_list or ip_ratelimit_list response.
+// Each line has the form "<name> <rate> limit <limit>".
+func ParseRateLimitList(raw string) ([]RateLimitEntry, error) {
+	entries := []RateLimitEntry{}
+
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || fields[2] != "limit" {
+			continue
+		}
+
+		rate, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, RateLimitEntry{
+			Name:    fields[0],
+			Rate:    rate,
+			Limit:   limit,
+			Limited: rate >= limit,
+		})
+	}
+
+	return entries, nil
+}
+
+// ParseRequestList parses the raw dump_requestlist response into RequestListEntry
+// values. The output is grouped under "thread #N" headers, each followed by a
+// column header and lines of the form "num type class name seconds module state".
+func ParseRequestList(raw string) ([]RequestListEntry, error) {
+	entries := []RequestListEntry{}
+	thread := 0
+
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "thread #") {
+			if v, err := strconv.Atoi(strings.TrimPrefix(line, "thread #")); err == nil {
+				thread = v
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+
+		// The column header starts with "#" and is skipped here
+		num, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		entry := RequestListEntry{
+			Thread: thread,
+			Number: num,
+			QType:  fields[1],
+			QClass: fields[2],
+			QName:  fields[3],
+			Module: fields[5],
+		}
+		if age, err := strconv.ParseFloat(fields[4], 64); err == nil {
+			entry.Age = age
+		}
+		if len(fields) > 6 {
+			entry.State = strings.Join(fields[6:], " ")
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// ParseCookieSecrets parses the raw print_cookie_secrets response. Lines have
+// the form "active : <hex>" or "staging: <hex>".
+func ParseCookieSecrets(raw string) ([]CookieSecret, error) {
+	secrets := []CookieSecret{}
+
+	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		state := strings.TrimSpace(parts[0])
+		secret := strings.TrimSpace(parts[1])
+		if secret == "" {
+			continue
+		}
+
+		secrets = append(secrets, CookieSecret{
+			State:  state,
+			Secret: secret,
+		})
+	}
+
+	return secrets, nil
+}
+
 // formatUptime converts seconds into a human-readable duration string
 func formatUptime(seconds int) string {
 	duration := time.Duration(seconds) * time.Second
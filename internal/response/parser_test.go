@@ -0,0 +1,169 @@
+package response
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLocalZones(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []LocalZone
+	}{
+		{"empty", "", []LocalZone{}},
+		{"zones", "localhost. redirect\nexample.com. static\n10.in-addr.arpa. nodefault\n", []LocalZone{
+			{Name: "localhost.", Type: "redirect"},
+			{Name: "example.com.", Type: "static"},
+			{Name: "10.in-addr.arpa.", Type: "nodefault"},
+		}},
+		{"malformed lines skipped", "example.com. static\nexample.org.\n\nexample.net. static extra\n", []LocalZone{
+			{Name: "example.com.", Type: "static"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLocalZones(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseLocalZones: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLocalZones = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocalData(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []LocalData
+	}{
+		{"empty", "", []LocalData{}},
+		{"records", "localhost.\t10800\tIN\tNS\tlocalhost.\n" +
+			"localhost.\t10800\tIN\tSOA\tlocalhost. nobody.invalid. 1 3600 1200 604800 10800\n" +
+			"www.example.com.\t3600\tIN\tA\t192.0.2.10\n", []LocalData{
+			{Name: "localhost.", TTL: 10800, Class: "IN", Type: "NS", RData: "localhost."},
+			{Name: "localhost.", TTL: 10800, Class: "IN", Type: "SOA", RData: "localhost. nobody.invalid. 1 3600 1200 604800 10800"},
+			{Name: "www.example.com.", TTL: 3600, Class: "IN", Type: "A", RData: "192.0.2.10"},
+		}},
+		{"malformed lines skipped", "www.example.com.\t3600\tIN\tA\n" +
+			"www.example.com.\tlong\tIN\tA\t192.0.2.10\n" +
+			"www.example.org.\t60\tIN\tAAAA\t2001:db8::1\n", []LocalData{
+			{Name: "www.example.org.", TTL: 60, Class: "IN", Type: "AAAA", RData: "2001:db8::1"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLocalData(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseLocalData: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLocalData = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRateLimitList(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []RateLimitEntry
+	}{
+		{"empty", "", []RateLimitEntry{}},
+		{"domains", "example.com. 120 limit 100\nexample.org. 12 limit 100\n", []RateLimitEntry{
+			{Name: "example.com.", Rate: 120, Limit: 100, Limited: true},
+			{Name: "example.org.", Rate: 12, Limit: 100},
+		}},
+		{"ips", "192.0.2.1 5 limit 5\n2001:db8::1 1 limit 5\n", []RateLimitEntry{
+			{Name: "192.0.2.1", Rate: 5, Limit: 5, Limited: true},
+			{Name: "2001:db8::1", Rate: 1, Limit: 5},
+		}},
+		{"malformed lines skipped", "example.com. 120 max 100\nexample.com. many limit 100\nexample.com. 120 limit\nexample.net. 3 limit 10\n", []RateLimitEntry{
+			{Name: "example.net.", Rate: 3, Limit: 10},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRateLimitList(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseRateLimitList: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRateLimitList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequestList(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []RequestListEntry
+	}{
+		{"empty", "", []RequestListEntry{}},
+		{"idle threads", "thread #0\n#   type cl name    seconds    module status\nthread #1\n#   type cl name    seconds    module status\n", []RequestListEntry{}},
+		{"requests", "thread #0\n" +
+			"#   type cl name    seconds    module status\n" +
+			"  0    A IN www.example.com. 0.012500 iterator wait for 192.0.2.53\n" +
+			"thread #1\n" +
+			"#   type cl name    seconds    module status\n" +
+			"  0 AAAA IN example.org. - validator\n", []RequestListEntry{
+			{Thread: 0, Number: 0, QType: "A", QClass: "IN", QName: "www.example.com.", Age: 0.0125, Module: "iterator", State: "wait for 192.0.2.53"},
+			{Thread: 1, Number: 0, QType: "AAAA", QClass: "IN", QName: "example.org.", Module: "validator"},
+		}},
+		{"malformed lines skipped", "thread #x\n  0 A IN short\n  x A IN www.example.com. 1.0 iterator\n  3 A IN example.net. 2.5 iterator\n", []RequestListEntry{
+			{Thread: 0, Number: 3, QType: "A", QClass: "IN", QName: "example.net.", Age: 2.5, Module: "iterator"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRequestList(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseRequestList: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRequestList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCookieSecrets(t *testing.T) {
+	const (
+		active  = "000102030405060708090a0b0c0d0e0f"
+		staging = "f0e0d0c0b0a090807060504030201000"
+	)
+	tests := []struct {
+		name string
+		raw  string
+		want []CookieSecret
+	}{
+		{"empty", "", []CookieSecret{}},
+		{"active only", "active : " + active + "\n", []CookieSecret{
+			{State: "active", Secret: active},
+		}},
+		{"active and staging", "active : " + active + "\nstaging: " + staging + "\n", []CookieSecret{
+			{State: "active", Secret: active},
+			{State: "staging", Secret: staging},
+		}},
+		{"malformed lines skipped", "no cookie secrets\nstaging:\nactive : " + active + "\n", []CookieSecret{
+			{State: "active", Secret: active},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCookieSecrets(tt.raw)
+			if err != nil {
+				t.Fatalf("ParseCookieSecrets: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCookieSecrets = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
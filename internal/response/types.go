@@ -1,5 +1,10 @@
 package response
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // CommonResponse is the base response structure for all API responses
 type CommonResponse struct {
 	Success bool        `json:"success"`
@@ -9,9 +14,10 @@ type CommonResponse struct {
 
 // Error represents an API error response
 type Error struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // StatusResponse represents the response from the status command
@@ -84,3 +90,145 @@ type CurrentRequests struct {
 	All  int `json:"all"`
 	User int `json:"user"`
 }
+
+// LocalZone represents an entry from list_local_zones
+type LocalZone struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// LocalData represents a resource record from list_local_data
+type LocalData struct {
+	Name  string `json:"name"`
+	TTL   int    `json:"ttl"`
+	Class string `json:"class"`
+	Type  string `json:"type"`
+	RData string `json:"rdata"`
+}
+
+// RateLimitEntry represents a domain or IP address from ratelimit_list or ip_ratelimit_list
+type RateLimitEntry struct {
+	Name    string `json:"name"`
+	Rate    int    `json:"rate"`
+	Limit   int    `json:"limit"`
+	Limited bool   `json:"limited"`
+}
+
+// RateLimitResponse combines Unbound's rate limit tables with the related query counter
+type RateLimitResponse struct {
+	Domains       []RateLimitEntry `json:"domains"`
+	IPs           []RateLimitEntry `json:"ips"`
+	IPRateLimited int              `json:"ip_ratelimited"`
+}
+
+// RequestListEntry represents a query in progress from dump_requestlist
+type RequestListEntry struct {
+	Thread int     `json:"thread"`
+	Number int     `json:"number"`
+	QName  string  `json:"qname"`
+	QType  string  `json:"qtype"`
+	QClass string  `json:"qclass"`
+	Age    float64 `json:"age"`
+	Module string  `json:"module"`
+	State  string  `json:"state"`
+}
+
+// ReloadResponse represents the result of a verified reload
+type ReloadResponse struct {
+	Message   string `json:"message"`
+	KeepCache bool   `json:"keep_cache"`
+	Version   string `json:"version"`
+	Uptime    Uptime `json:"uptime"`
+}
+
+// StopConfirmation is returned when a stop request still needs to be confirmed
+type StopConfirmation struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Message   string    `json:"message"`
+}
+
+// CookieSecret represents a server cookie secret from print_cookie_secrets.
+// Secret is only populated for callers with elevated permissions.
+type CookieSecret struct {
+	State       string `json:"state"`
+	Secret      string `json:"secret,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	Redacted    bool   `json:"redacted"`
+}
+
+// BreakerStatus is a snapshot of the client's circuit breaker
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// HealthResponse represents the connectivity health of the Unbound control socket
+type HealthResponse struct {
+	SocketReachable bool          `json:"socket_reachable"`
+	Breaker         BreakerStatus `json:"breaker"`
+}
+
+// LivenessResponse is returned by the liveness probe
+type LivenessResponse struct {
+	Status string `json:"status"`
+	Uptime string `json:"uptime"`
+}
+
+// ReadinessResponse is returned by the readiness probe
+type ReadinessResponse struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]HealthCheck `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+// HealthCheck is the result of a single readiness check
+type HealthCheck struct {
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKey is the metadata of a managed API key. Secret is only set in the
+// response that creates or rotates the key.
+type APIKey struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Roles             []string   `json:"roles,omitempty"`
+	Scopes            []string   `json:"scopes,omitempty"`
+	AllowCIDRs        []string   `json:"allow_cidrs,omitempty"`
+	DenyCIDRs         []string   `json:"deny_cidrs,omitempty"`
+	State             string     `json:"state"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	Secret            string     `json:"secret,omitempty"`
+}
+
+// ChangeRequest is a call to an operation that needs approval. Result is set
+// once an approved call has run.
+type ChangeRequest struct {
+	ID           string        `json:"id"`
+	Operation    string        `json:"operation"`
+	Method       string        `json:"method"`
+	Path         string        `json:"path"`
+	Query        string        `json:"query,omitempty"`
+	RequestedBy  string        `json:"requested_by"`
+	RequestedVia string        `json:"requested_via"`
+	State        string        `json:"state"`
+	CreatedAt    time.Time     `json:"created_at"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	DecidedBy    string        `json:"decided_by,omitempty"`
+	DecidedAt    *time.Time    `json:"decided_at,omitempty"`
+	Result       *ChangeResult `json:"result,omitempty"`
+}
+
+// ChangeResult is the response of an approved call
+type ChangeResult struct {
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
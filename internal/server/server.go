@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/callMe-Root/unbound-control-api/internal/config"
 	"github.com/callMe-Root/unbound-control-api/internal/middleware"
 	"github.com/callMe-Root/unbound-control-api/internal/unbound"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
 	"github.com/gorilla/mux"
 )
 
@@ -24,26 +26,36 @@ type Server struct {
 	router     *mux.Router
 	certFile   string
 	keyFile    string
+	configPath string
 	mu         sync.RWMutex
 	config     *config.Config
 	client     *unbound.Client
+	authn      middleware.Authenticators
+	sources    *middleware.SourcePolicy
+	cors       *middleware.CORSPolicy
+	limiter    *middleware.RateLimiter
 }
 
-// New creates a new server instance
-func New(host string, port int, certFile, keyFile string, cfg *config.Config, client *unbound.Client) *Server {
+// New creates a new server instance. cors is nil when CORS is disabled.
+func New(host string, port int, certFile, keyFile, configPath string, cfg *config.Config, client *unbound.Client, authn middleware.Authenticators, sources *middleware.SourcePolicy, cors *middleware.CORSPolicy, limiter *middleware.RateLimiter) *Server {
 	router := mux.NewRouter()
 	addr := fmt.Sprintf("%s:%d", host, port)
 
 	return &Server{
 		httpServer: &http.Server{
 			Addr:    addr,
-			Handler: router,
+			Handler: middleware.SecurityHeaders(cfg.Server.HSTSMaxAge)(router),
 		},
-		router:   router,
-		certFile: certFile,
-		keyFile:  keyFile,
-		config:   cfg,
-		client:   client,
+		router:     router,
+		certFile:   certFile,
+		keyFile:    keyFile,
+		configPath: configPath,
+		config:     cfg,
+		client:     client,
+		authn:      authn,
+		sources:    sources,
+		cors:       cors,
+		limiter:    limiter,
 	}
 }
 
@@ -52,34 +64,89 @@ func (s *Server) Router() *mux.Router {
 	return s.router
 }
 
+// Config returns the configuration in effect, which changes when it is
+// reloaded
+func (s *Server) Config() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// Client returns the Unbound client in use, which is replaced when the
+// control socket changes on reload
+func (s *Server) Client() *unbound.Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
 // reloadConfig reloads the configuration and updates the server accordingly
 func (s *Server) reloadConfig() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Load new configuration
-	newCfg, err := config.LoadConfig("config.yaml")
+	newCfg, err := config.LoadConfig(s.configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load new configuration: %w", err)
 	}
 
-	// Update API key authentication
-	s.router.Use(middleware.APIKeyAuth(newCfg.Security.APIKey))
+	// Check every new setting before changing any, so a bad value leaves the
+	// previous configuration in effect rather than half of the new one. The
+	// router keeps using the same authenticators and policies, which are
+	// updated in place.
+	type step struct {
+		what    string
+		prepare func() (func(), error)
+	}
+	steps := []step{
+		{"API keys", func() (func(), error) { return s.authn.Keys.Prepare(newCfg.Security) }},
+		{"source address lists", func() (func(), error) { return s.sources.Prepare(newCfg.Security) }},
+	}
+	if s.authn.JWT != nil {
+		steps = append(steps, step{"JWT settings", func() (func(), error) {
+			return s.authn.JWT.Prepare(context.Background(), newCfg.Security.JWT)
+		}})
+	}
+	if s.authn.Signatures != nil {
+		steps = append(steps, step{"signing keys", func() (func(), error) { return s.authn.Signatures.Prepare(newCfg.Security.Signing) }})
+	}
+	if s.cors != nil {
+		steps = append(steps, step{"CORS settings", func() (func(), error) { return s.cors.Prepare(newCfg.CORS) }})
+	}
+	if s.authn.ClientCerts != nil {
+		steps = append(steps, step{"client certificate mappings", func() (func(), error) { return s.authn.ClientCerts.Prepare(newCfg.Security.ClientCerts) }})
+	}
 
-	// Update rate limiting
-	s.router.Use(middleware.RateLimit(newCfg.RateLimit.RequestsPerSecond, newCfg.RateLimit.BurstSize))
+	applies := make([]func(), 0, len(steps))
+	for _, st := range steps {
+		apply, err := st.prepare()
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", st.what, err)
+		}
+		applies = append(applies, apply)
+	}
 
-	// Update Unbound client settings if changed
+	// Create a new Unbound client if the control socket changed
+	var newClient *unbound.Client
 	if newCfg.Unbound.ControlSocket != s.config.Unbound.ControlSocket {
-		// Create new client with updated settings
-		newClient, err := unbound.NewClient(newCfg.Unbound.ControlSocket)
+		clientOpts := unbound.OptionsFromConfig(newCfg.Unbound)
+		clientOpts.Logger = logger.Get()
+		newClient, err = unbound.NewClient(newCfg.Unbound.ControlSocket, clientOpts)
 		if err != nil {
 			return fmt.Errorf("failed to create new Unbound client: %w", err)
 		}
+	}
+
+	// Everything checked out, switch over
+	for _, apply := range applies {
+		apply()
+	}
+	s.limiter.Update(newCfg.RateLimit)
+	if newClient != nil {
 		s.client.Close()
 		s.client = newClient
 	}
-
 	// Update server configuration
 	s.config = newCfg
 
@@ -112,6 +179,49 @@ func (s *Server) reloadCertificate() error {
 	return nil
 }
 
+// clientAuthModes maps server.client_auth to the TLS client authentication policy
+var clientAuthModes = map[string]tls.ClientAuthType{
+	"":        tls.NoClientCert,
+	"none":    tls.NoClientCert,
+	"request": tls.VerifyClientCertIfGiven,
+	"require": tls.RequireAndVerifyClientCert,
+}
+
+// configureClientAuth sets up client certificate verification against the
+// configured CA bundle
+func (s *Server) configureClientAuth() error {
+	cfg := s.config.Server
+	mode, ok := clientAuthModes[cfg.ClientAuth]
+	if !ok {
+		return fmt.Errorf("invalid client_auth %q, use none, request or require", cfg.ClientAuth)
+	}
+	if mode == tls.NoClientCert {
+		return nil
+	}
+	if s.certFile == "" || s.keyFile == "" {
+		return fmt.Errorf("client_auth %q requires TLS", cfg.ClientAuth)
+	}
+	if cfg.ClientCAFile == "" {
+		return fmt.Errorf("client_auth %q requires client_ca_file", cfg.ClientAuth)
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+	}
+
+	if s.httpServer.TLSConfig == nil {
+		s.httpServer.TLSConfig = &tls.Config{}
+	}
+	s.httpServer.TLSConfig.ClientCAs = pool
+	s.httpServer.TLSConfig.ClientAuth = mode
+	return nil
+}
+
 // Start starts the server with TLS support
 func (s *Server) Start() error {
 	// Create a channel to listen for errors coming from the server
@@ -121,6 +231,10 @@ func (s *Server) Start() error {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
+	if err := s.configureClientAuth(); err != nil {
+		return err
+	}
+
 	// Start the server
 	go func() {
 		if s.certFile != "" && s.keyFile != "" {
@@ -0,0 +1,138 @@
+package unbound
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// ErrUnreachable is returned when the control socket cannot be reached,
+// including when the circuit breaker is open and the command was not attempted
+var ErrUnreachable = errors.New("unbound unreachable")
+
+// Breaker states as reported by BreakerStatus
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+const (
+	// DefaultBreakerThreshold is used when Options.BreakerThreshold is not set
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is used when Options.BreakerCooldown is not set
+	DefaultBreakerCooldown = 10 * time.Second
+)
+
+// breaker is a circuit breaker around the control socket. After threshold
+// consecutive connection failures it opens and commands fail fast; once the
+// cooldown has passed a single probe is let through to close it again.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	lastErr   error
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+// allow reports whether a command may be attempted
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		// Only one probe at a time while half-open
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// success records a successful command and closes the breaker
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = BreakerClosed
+	b.failures = 0
+	b.lastErr = nil
+	b.probing = false
+}
+
+// failure records a connection failure and reports whether the breaker opened
+func (b *breaker) failure(err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastErr = err
+	b.probing = false
+
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		opened := b.state != BreakerOpen
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		return opened
+	}
+	return false
+}
+
+// abandon releases a probe slot when a command was cancelled by its caller
+// before its outcome was known
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// status returns a snapshot of the breaker
+func (b *breaker) status() response.BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := response.BreakerStatus{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.lastErr != nil {
+		status.LastError = b.lastErr.Error()
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
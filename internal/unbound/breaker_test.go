@@ -0,0 +1,115 @@
+package unbound
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	errDown := errors.New("connection refused")
+	const cooldown = 20 * time.Millisecond
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, b *breaker)
+		want string
+	}{
+		{"stays closed below threshold", func(t *testing.T, b *breaker) {
+			b.failure(errDown)
+			b.failure(errDown)
+			if !b.allow() {
+				t.Error("closed breaker refused a command")
+			}
+		}, BreakerClosed},
+		{"opens at threshold", func(t *testing.T, b *breaker) {
+			b.failure(errDown)
+			b.failure(errDown)
+			if !b.failure(errDown) {
+				t.Error("failure did not report opening")
+			}
+			if b.allow() {
+				t.Error("open breaker allowed a command")
+			}
+		}, BreakerOpen},
+		{"success resets failures", func(t *testing.T, b *breaker) {
+			b.failure(errDown)
+			b.failure(errDown)
+			b.success()
+			b.failure(errDown)
+		}, BreakerClosed},
+		{"half-open lets one probe through", func(t *testing.T, b *breaker) {
+			openBreaker(b, errDown)
+			time.Sleep(cooldown)
+			if !b.allow() {
+				t.Fatal("probe refused after cooldown")
+			}
+			if b.allow() {
+				t.Error("second probe allowed while half-open")
+			}
+		}, BreakerHalfOpen},
+		{"successful probe closes", func(t *testing.T, b *breaker) {
+			openBreaker(b, errDown)
+			time.Sleep(cooldown)
+			b.allow()
+			b.success()
+		}, BreakerClosed},
+		{"failed probe reopens", func(t *testing.T, b *breaker) {
+			openBreaker(b, errDown)
+			time.Sleep(cooldown)
+			b.allow()
+			b.failure(errDown)
+			if b.allow() {
+				t.Error("reopened breaker allowed a command before the cooldown")
+			}
+		}, BreakerOpen},
+		{"abandoned probe frees the slot", func(t *testing.T, b *breaker) {
+			openBreaker(b, errDown)
+			time.Sleep(cooldown)
+			b.allow()
+			b.abandon()
+			if !b.allow() {
+				t.Error("probe refused after the previous one was abandoned")
+			}
+		}, BreakerHalfOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(3, cooldown)
+			tt.run(t, b)
+			if got := b.status().State; got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func openBreaker(b *breaker, err error) {
+	for i := 0; i < b.threshold; i++ {
+		b.failure(err)
+	}
+}
+
+func TestClientFailsFastWhenOpen(t *testing.T) {
+	c := newTestClient(t, filepath.Join(t.TempDir(), "missing.ctl"), Options{
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Hour,
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.SendCommand(ctx, "status"); !errors.Is(err, ErrUnreachable) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrUnreachable)
+		}
+	}
+	if state := c.BreakerStatus().State; state != BreakerOpen {
+		t.Fatalf("state = %s, want %s", state, BreakerOpen)
+	}
+
+	_, err := c.SendCommand(ctx, "status")
+	if !errors.Is(err, ErrUnreachable) || c.BreakerStatus().ConsecutiveFailures != 2 {
+		t.Errorf("error = %v after %d failures, want a fast %v", err, c.BreakerStatus().ConsecutiveFailures, ErrUnreachable)
+	}
+}
@@ -2,128 +2,447 @@ package unbound
 
 import (
 	"bufio"
+	"context"
 	"fmt"
-	"log"
-	"net"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/callMe-Root/unbound-control-api/internal/audit"
+	"github.com/callMe-Root/unbound-control-api/internal/config"
+	"github.com/callMe-Root/unbound-control-api/internal/metrics"
 	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/callMe-Root/unbound-control-api/internal/tracing"
+	"github.com/callMe-Root/unbound-control-api/pkg/logger"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// DefaultMaxConcurrent is used when Options.MaxConcurrent is not set
+	DefaultMaxConcurrent = 4
+
+	// DefaultTimeout is used when Options.Timeout is not set
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultMaxIdle is used when Options.MaxIdle is not set
+	DefaultMaxIdle = 30 * time.Second
+
+	// DefaultRetryBackoff is used when Options.RetryBackoff is not set
+	DefaultRetryBackoff = 100 * time.Millisecond
+
+	// DefaultRetryMaxBackoff is used when Options.RetryMaxBackoff is not set
+	DefaultRetryMaxBackoff = 2 * time.Second
+)
+
+// idempotentCommands may be resent when the connection fails after the
+// command was written, because repeating them has no further effect.
+// Note that "stats" is not listed as it resets the counters.
+var idempotentCommands = map[string]bool{
+	"status":                 true,
+	"stats_noreset":          true,
+	"list_local_zones":       true,
+	"list_local_data":        true,
+	"view_list_local_zones":  true,
+	"view_list_local_data":   true,
+	"ratelimit_list":         true,
+	"ip_ratelimit_list":      true,
+	"dump_requestlist":       true,
+	"print_cookie_secrets":   true,
+	"flush":                  true,
+	"flush_zone":             true,
+	"local_zone_remove":      true,
+	"local_data_remove":      true,
+	"view_local_zone_remove": true,
+	"view_local_data_remove": true,
+}
+
+// sensitiveCommands have arguments that must never be logged
+var sensitiveCommands = map[string]bool{
+	"add_cookie_secret": true,
+}
+
+// logCommand returns cmd as it may appear in logs
+func logCommand(cmd string) string {
+	name, _, hasArgs := strings.Cut(cmd, " ")
+	if hasArgs && sensitiveCommands[name] {
+		return name + " [REDACTED]"
+	}
+	return cmd
+}
+
+// isIdempotent reports whether cmd can safely be sent twice
+func isIdempotent(cmd string) bool {
+	name, _, _ := strings.Cut(cmd, " ")
+	return idempotentCommands[name]
+}
+
+// Options tunes how the client talks to the control socket
+type Options struct {
+	// MaxConcurrent limits the commands in flight at once
+	MaxConcurrent int
+	// PoolSize is the number of connections dialed ahead of time, 0 disables the pool
+	PoolSize int
+	// MaxIdle is how long a pre-dialed connection may wait before it is discarded
+	MaxIdle time.Duration
+	// Timeout bounds dialing and each command round trip
+	Timeout time.Duration
+	// RetryAttempts is the number of attempts per command, including the first
+	RetryAttempts int
+	// RetryBackoff is the delay before the first retry, doubled on every attempt
+	RetryBackoff time.Duration
+	// RetryMaxBackoff caps the delay between retries
+	RetryMaxBackoff time.Duration
+	// BreakerThreshold is the number of consecutive failures that opens the circuit breaker
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a probe is allowed
+	BreakerCooldown time.Duration
+	// Logger receives the client's log events, the global logger is used when nil
+	Logger *zerolog.Logger
+	// TraceLines logs every line read from the socket at debug level. Replies
+	// can contain whole cache dumps, so this is off by default.
+	TraceLines bool
+}
+
+// OptionsFromConfig builds client options from the unbound configuration section
+func OptionsFromConfig(cfg config.UnboundConfig) Options {
+	return Options{
+		MaxConcurrent:    cfg.MaxConcurrent,
+		PoolSize:         cfg.PoolSize,
+		MaxIdle:          cfg.MaxIdle,
+		Timeout:          cfg.Timeout,
+		RetryAttempts:    cfg.Retry.Attempts,
+		RetryBackoff:     cfg.Retry.Backoff,
+		RetryMaxBackoff:  cfg.Retry.MaxBackoff,
+		BreakerThreshold: cfg.Breaker.Threshold,
+		BreakerCooldown:  cfg.Breaker.Cooldown,
+		TraceLines:       cfg.TraceLines,
+	}
+}
+
 type Client struct {
 	socketPath string
-	logger     *log.Logger
+	logger     zerolog.Logger
+	traceLines bool
+	timeout    time.Duration
+	sem        chan struct{}
+	breaker    *breaker
+
+	retryAttempts   int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+
+	pool      chan pooledConn
+	maxIdle   time.Duration
+	refill    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewClient(socketPath string) (*Client, error) {
-	logger := log.New(log.Writer(), "[UnboundClient] ", log.LstdFlags|log.Lmicroseconds)
-	logger.Printf("Initializing client for UNIX socket: %s", socketPath)
-	return &Client{
+func NewClient(socketPath string, opts Options) (*Client, error) {
+	base := opts.Logger
+	if base == nil {
+		base = logger.Get()
+	}
+	log := base.With().
+		Str("component", "unbound").
+		Str("instance", socketPath).
+		Logger()
+	log.Info().Msg("initializing client for UNIX socket")
+
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = DefaultMaxIdle
+	}
+	if opts.RetryAttempts <= 0 {
+		opts.RetryAttempts = 1
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	if opts.RetryMaxBackoff <= 0 {
+		opts.RetryMaxBackoff = DefaultRetryMaxBackoff
+	}
+
+	c := &Client{
 		socketPath: socketPath,
-		logger:     logger,
-	}, nil
+		logger:     log,
+		traceLines: opts.TraceLines,
+		timeout:    opts.Timeout,
+		sem:        make(chan struct{}, opts.MaxConcurrent),
+		breaker:    newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		maxIdle:    opts.MaxIdle,
+		done:       make(chan struct{}),
+
+		retryAttempts:   opts.RetryAttempts,
+		retryBackoff:    opts.RetryBackoff,
+		retryMaxBackoff: opts.RetryMaxBackoff,
+	}
+
+	if opts.PoolSize > 0 {
+		c.pool = make(chan pooledConn, opts.PoolSize)
+		c.refill = make(chan struct{}, 1)
+		go c.maintainPool()
+	}
+
+	return c, nil
 }
 
-func (c *Client) SendCommand(cmd string) (string, error) {
-	c.logger.Printf("Connecting to Unbound control UNIX socket: %s", c.socketPath)
-	conn, err := net.Dial("unix", c.socketPath)
+// SendCommand sends a command to Unbound and returns its raw reply. Connection
+// failures are retried with exponential backoff when it is safe to do so:
+// always if the command was never written, otherwise only for idempotent
+// commands. Errors caused by connectivity wrap ErrUnreachable.
+func (c *Client) SendCommand(ctx context.Context, cmd string) (string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "unbound "+commandName(cmd),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("unbound.command", commandName(cmd)),
+			attribute.String("unbound.instance", c.socketPath),
+		),
+	)
+
+	audit.RecordCommand(ctx, logCommand(cmd))
+
+	start := time.Now()
+	resp, err := c.sendWithRetry(ctx, cmd)
+	metrics.CommandDuration.WithLabelValues(commandName(cmd)).Observe(time.Since(start).Seconds())
 	if err != nil {
-		c.logger.Printf("Failed to connect to socket: %v", err)
-		return "", fmt.Errorf("failed to connect to socket: %w", err)
+		metrics.CommandErrors.WithLabelValues(commandName(cmd)).Inc()
+	} else {
+		span.SetAttributes(attribute.Int("unbound.response.bytes", len(resp)))
+	}
+	endSpan(span, err)
+	return resp, err
+}
+
+// sendWithRetry applies the breaker and retry policy around send
+func (c *Client) sendWithRetry(ctx context.Context, cmd string) (string, error) {
+	log := logger.FromContext(ctx, c.logger)
+	backoff := c.retryBackoff
+
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return "", fmt.Errorf("%w: circuit breaker open", ErrUnreachable)
+		}
+
+		resp, sent, err := c.send(ctx, cmd)
+		if err == nil {
+			c.breaker.success()
+			return resp, nil
+		}
+
+		// A caller giving up says nothing about Unbound's health
+		if ctx.Err() != nil {
+			c.breaker.abandon()
+			return "", err
+		}
+
+		if c.breaker.failure(err) {
+			log.Warn().Err(err).Msg("circuit breaker opened")
+		}
+		// Pooled connections may point at an Unbound that has gone away
+		c.reconnect()
+
+		if attempt >= c.retryAttempts || (sent && !isIdempotent(cmd)) {
+			return "", fmt.Errorf("%w: %w", ErrUnreachable, err)
+		}
+
+		log.Debug().
+			Err(err).
+			Str("command", logCommand(cmd)).
+			Dur("backoff", backoff).
+			Int("attempt", attempt+1).
+			Int("max_attempts", c.retryAttempts).
+			Msg("retrying command")
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		backoff = min(backoff*2, c.retryMaxBackoff)
+	}
+}
+
+// send performs a single command round trip. sent reports whether the command
+// may have reached Unbound.
+func (c *Client) send(ctx context.Context, cmd string) (resp string, sent bool, err error) {
+	// Limit concurrent commands to protect Unbound from bursts
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+	defer func() { <-c.sem }()
+
+	log := logger.FromContext(ctx, c.logger)
+	start := time.Now()
+	command := logCommand(cmd)
+
+	_, dialSpan := startSpan(ctx, "dial")
+	conn, err := c.getConn()
+	endSpan(dialSpan, err)
+	if err != nil {
+		log.Error().Err(err).Str("command", command).Msg("failed to connect to socket")
+		return "", false, fmt.Errorf("failed to connect to socket: %w", err)
 	}
 	defer conn.Close()
 
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return "", false, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
 	// Format command with UBCT1  prefix and newline
 	fullCmd := fmt.Sprintf("UBCT1  %s\n", cmd)
-	c.logger.Printf("Sending command: %q", fullCmd)
-	_, err = conn.Write([]byte(fullCmd))
+	_, writeSpan := startSpan(ctx, "write")
+	n, err := conn.Write([]byte(fullCmd))
+	endSpan(writeSpan, err)
 	if err != nil {
-		c.logger.Printf("Failed to write command: %v", err)
-		return "", fmt.Errorf("failed to write command: %w", err)
+		log.Error().Err(err).Str("command", command).Msg("failed to write command")
+		return "", n > 0, fmt.Errorf("failed to write command: %w", err)
 	}
 
 	// Read and return the response
-	c.logger.Printf("Reading response...")
+	_, readSpan := startSpan(ctx, "read")
 	var response strings.Builder
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
 		line := scanner.Text()
-		c.logger.Printf("Read line: %q", line)
+		if c.traceLines {
+			log.Debug().Str("command", command).Str("line", line).Msg("read line")
+		}
 		response.WriteString(line)
 		response.WriteString("\n")
 	}
+	readSpan.SetAttributes(attribute.Int("unbound.response.bytes", response.Len()))
+	endSpan(readSpan, scanner.Err())
 	if err := scanner.Err(); err != nil {
-		c.logger.Printf("Error reading response: %v", err)
-		return "", fmt.Errorf("error reading response: %w", err)
+		log.Error().Err(err).Str("command", command).Msg("error reading response")
+		return "", true, fmt.Errorf("error reading response: %w", err)
 	}
 
 	respStr := strings.TrimSpace(response.String())
-	c.logger.Printf("Received response: %s", respStr)
-	return respStr, nil
+	log.Debug().
+		Str("command", command).
+		Dur("duration", time.Since(start)).
+		Int("bytes", response.Len()).
+		Msg("command completed")
+	return respStr, true, nil
 }
 
+// Close stops the connection pool and closes any pre-dialed connections
 func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
 	return nil
 }
 
 // Status returns the server status
-func (c *Client) Status() (*response.StatusResponse, error) {
-	raw, err := c.SendCommand("status")
+func (c *Client) Status(ctx context.Context) (*response.StatusResponse, error) {
+	raw, err := c.SendCommand(ctx, "status")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
-	return response.ParseStatusResponse(raw)
+	return parseTraced(ctx, response.ParseStatusResponse, raw)
 }
 
 // Stats returns the server statistics
-func (c *Client) Stats() (*response.StatsResponse, error) {
-	raw, err := c.SendCommand("stats")
+func (c *Client) Stats(ctx context.Context) (*response.StatsResponse, error) {
+	raw, err := c.SendCommand(ctx, "stats")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
-	return response.ParseStatsResponse(raw)
+	return parseTraced(ctx, response.ParseStatsResponse, raw)
+}
+
+// StatsNoReset returns the server statistics without resetting Unbound's counters
+func (c *Client) StatsNoReset(ctx context.Context) (*response.StatsResponse, error) {
+	raw, err := c.SendCommand(ctx, "stats_noreset")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+	return parseTraced(ctx, response.ParseStatsResponse, raw)
 }
 
 // Reload reloads the server configuration
-func (c *Client) Reload() error {
-	_, err := c.SendCommand("reload")
+func (c *Client) Reload(ctx context.Context) error {
+	raw, err := c.SendCommand(ctx, "reload")
 	if err != nil {
 		return fmt.Errorf("failed to reload: %w", err)
 	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to reload: %w", err)
+	}
 	return nil
 }
 
 // Flush flushes the cache for a domain
-func (c *Client) Flush(domain string) error {
+func (c *Client) Flush(ctx context.Context, domain string) error {
 	cmd := fmt.Sprintf("flush %s", domain)
-	_, err := c.SendCommand(cmd)
+	_, err := c.SendCommand(ctx, cmd)
 	if err != nil {
 		return fmt.Errorf("failed to flush domain %s: %w", domain, err)
 	}
 	return nil
 }
 
+// FlushZone flushes the cache for a domain and every name below it
+func (c *Client) FlushZone(ctx context.Context, domain string) error {
+	raw, err := c.SendCommand(ctx, fmt.Sprintf("flush_zone %s", domain))
+	if err != nil {
+		return fmt.Errorf("failed to flush zone %s: %w", domain, err)
+	}
+	return checkOK(raw)
+}
+
 // TestConnection verifies that the connection to Unbound is working
-func (c *Client) TestConnection() error {
-	c.logger.Printf("Testing connection to Unbound control UNIX socket: %s", c.socketPath)
+func (c *Client) TestConnection(ctx context.Context) error {
+	log := logger.FromContext(ctx, c.logger)
 
 	// Try to get status
-	status, err := c.Status()
+	status, err := c.Status(ctx)
 	if err != nil {
-		c.logger.Printf("Connection test failed: %v", err)
+		log.Warn().Err(err).Msg("connection test failed")
 		return fmt.Errorf("connection test failed: %w", err)
 	}
 
-	c.logger.Printf("Connected to Unbound version: %s", status.Version)
+	log.Debug().Str("version", status.Version).Msg("connection test succeeded")
 	return nil
 }
 
-// VerifyConnection checks if the connection is still alive
+// VerifyConnection checks that the control socket accepts connections
+// without sending a command
 func (c *Client) VerifyConnection() bool {
+	conn, err := c.dial()
+	if err != nil {
+		return false
+	}
+	conn.Close()
 	return true
 }
 
-// reconnect attempts to reestablish the connection
-func (c *Client) reconnect() error {
-	return nil
+// BreakerStatus returns the state of the circuit breaker
+func (c *Client) BreakerStatus() response.BreakerStatus {
+	return c.breaker.status()
+}
+
+// reconnect discards pre-dialed connections, which are useless once Unbound
+// restarts, and lets the pool dial fresh ones
+func (c *Client) reconnect() {
+	if c.pool == nil {
+		return
+	}
+	c.drainPool()
+	c.requestRefill()
 }
@@ -0,0 +1,207 @@
+package unbound
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeUnbound answers every command on a unix socket with reply, closing the
+// connection afterwards as unbound-control does
+func fakeUnbound(tb testing.TB, reply string) string {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "unbound.ctl")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		tb.Fatalf("listen: %v", err)
+	}
+	tb.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil || !strings.HasPrefix(line, "UBCT1 ") {
+					return
+				}
+				conn.Write([]byte(reply))
+			}(conn)
+		}
+	}()
+	return path
+}
+
+// silentUnbound reads each command on a unix socket and never answers, so
+// the client fails after writing it. It returns the number of connections.
+func silentUnbound(tb testing.TB) (string, *atomic.Int32) {
+	tb.Helper()
+
+	path := filepath.Join(tb.TempDir(), "unbound.ctl")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		tb.Fatalf("listen: %v", err)
+	}
+	tb.Cleanup(func() { ln.Close() })
+
+	conns := new(atomic.Int32)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				// Block until the client gives up and closes the connection
+				io.Copy(io.Discard, conn)
+			}(conn)
+		}
+	}()
+	return path, conns
+}
+
+func newTestClient(tb testing.TB, socket string, opts Options) *Client {
+	tb.Helper()
+
+	nop := zerolog.Nop()
+	opts.Logger = &nop
+	c, err := NewClient(socket, opts)
+	if err != nil {
+		tb.Fatalf("NewClient: %v", err)
+	}
+	tb.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSendCommand(t *testing.T) {
+	for _, poolSize := range []int{0, 2} {
+		c := newTestClient(t, fakeUnbound(t, "ok\n"), Options{PoolSize: poolSize})
+		resp, err := c.SendCommand(context.Background(), "status")
+		if err != nil {
+			t.Fatalf("pool %d: SendCommand: %v", poolSize, err)
+		}
+		if resp != "ok" {
+			t.Errorf("pool %d: got %q, want %q", poolSize, resp, "ok")
+		}
+	}
+}
+
+func TestReloadErrorReply(t *testing.T) {
+	c := newTestClient(t, fakeUnbound(t, "error could not reload\n"), Options{})
+	if err := c.Reload(context.Background()); err == nil {
+		t.Fatal("Reload ignored an error reply")
+	}
+
+	c = newTestClient(t, fakeUnbound(t, "ok\n"), Options{})
+	if err := c.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		cmd   string
+		conns int32
+	}{
+		// Sent but unanswered: only idempotent commands may be sent again
+		{"reload", 1},
+		{"stop", 1},
+		{"status", 3},
+		{"stats_noreset", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			socket, conns := silentUnbound(t)
+			c := newTestClient(t, socket, Options{
+				Timeout:          50 * time.Millisecond,
+				RetryAttempts:    3,
+				RetryBackoff:     time.Millisecond,
+				BreakerThreshold: 10,
+			})
+
+			if _, err := c.SendCommand(context.Background(), tt.cmd); !errors.Is(err, ErrUnreachable) {
+				t.Fatalf("error = %v, want %v", err, ErrUnreachable)
+			}
+			if got := conns.Load(); got != tt.conns {
+				t.Errorf("command sent on %d connections, want %d", got, tt.conns)
+			}
+		})
+	}
+}
+
+func TestRetryUnsent(t *testing.T) {
+	// The socket only appears after the first attempt failed to dial, so a
+	// non-idempotent command is retried as it never reached Unbound
+	socket := filepath.Join(t.TempDir(), "unbound.ctl")
+	c := newTestClient(t, socket, Options{
+		RetryAttempts: 3,
+		RetryBackoff:  100 * time.Millisecond,
+	})
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			close(listening)
+			return
+		}
+		listening <- ln
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		bufio.NewReader(conn).ReadString('\n')
+		conn.Write([]byte("ok\n"))
+		conn.Close()
+	}()
+
+	resp, err := c.SendCommand(context.Background(), "reload")
+	if ln, ok := <-listening; ok {
+		ln.Close()
+	}
+	if err != nil || resp != "ok" {
+		t.Fatalf("SendCommand = %q, %v, want ok after a retry", resp, err)
+	}
+}
+
+func benchmarkSendCommand(b *testing.B, poolSize int) {
+	c := newTestClient(b, fakeUnbound(b, "ok\n"), Options{
+		PoolSize:      poolSize,
+		MaxConcurrent: 8,
+	})
+	ctx := context.Background()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.SendCommand(ctx, "status"); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkSendCommandNoPool(b *testing.B) {
+	benchmarkSendCommand(b, 0)
+}
+
+func BenchmarkSendCommandPool(b *testing.B) {
+	benchmarkSendCommand(b, 4)
+}
@@ -0,0 +1,96 @@
+package unbound
+
+import (
+	"context"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsMetric describes one value exported from StatsResponse
+type statsMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(s *response.StatsResponse) float64
+}
+
+// StatsCollector exports Unbound's statistics to Prometheus. It uses
+// stats_noreset so scrapes do not reset Unbound's counters.
+type StatsCollector struct {
+	client  func() *Client
+	up      *prometheus.Desc
+	metrics []statsMetric
+}
+
+// NewStatsCollector creates a collector that queries the client returned by
+// client on every scrape, so it follows a change of control socket
+func NewStatsCollector(client func() *Client) *StatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("unbound", "", name), help, nil, nil)
+	}
+
+	return &StatsCollector{
+		client: client,
+		up:     desc("up", "Whether the last stats_noreset command succeeded."),
+		metrics: []statsMetric{
+			{desc("queries_total", "Queries received."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Queries.Total) }},
+			{desc("queries_ip_ratelimited_total", "Queries dropped by ip-ratelimit."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Queries.IPRateLimited) }},
+			{desc("cache_hits_total", "Queries answered from cache."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Cache.Hits) }},
+			{desc("cache_misses_total", "Queries that needed recursion."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Cache.Misses) }},
+			{desc("prefetch_total", "Cache prefetches performed."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Cache.Prefetch) }},
+			{desc("zero_ttl_total", "Replies served with an expired TTL."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Cache.ZeroTTL) }},
+			{desc("recursive_replies_total", "Replies sent after recursion."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.Recursion.Replies) }},
+			{desc("recursion_time_avg_seconds", "Average recursion time."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return s.Recursion.Time.Average }},
+			{desc("recursion_time_median_seconds", "Median recursion time."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return s.Recursion.Time.Median }},
+			{desc("requestlist_avg", "Average size of the request list."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return s.RequestList.Average }},
+			{desc("requestlist_max", "Maximum size of the request list."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return float64(s.RequestList.Max) }},
+			{desc("requestlist_overwritten_total", "Requests replaced by newer ones."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.RequestList.Overwritten) }},
+			{desc("requestlist_exceeded_total", "Requests dropped because the list was full."), prometheus.CounterValue,
+				func(s *response.StatsResponse) float64 { return float64(s.RequestList.Exceeded) }},
+			{desc("requestlist_current_all", "Requests currently in the request list."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return float64(s.RequestList.Current.All) }},
+			{desc("requestlist_current_user", "Client requests currently in the request list."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return float64(s.RequestList.Current.User) }},
+			{desc("tcp_usage_ratio", "Fraction of incoming TCP buffers in use."), prometheus.GaugeValue,
+				func(s *response.StatsResponse) float64 { return s.TCPUsage }},
+		},
+	}
+}
+
+// Describe implements prometheus.Collector
+func (sc *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sc.up
+	for _, m := range sc.metrics {
+		ch <- m.desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (sc *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	client := sc.client()
+	ctx, cancel := context.WithTimeout(context.Background(), client.timeout)
+	defer cancel()
+
+	stats, err := client.StatsNoReset(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(sc.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(sc.up, prometheus.GaugeValue, 1)
+	for _, m := range sc.metrics {
+		ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, m.value(stats))
+	}
+}
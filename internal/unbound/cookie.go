@@ -0,0 +1,56 @@
+package unbound
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// CookieSecrets returns the active and staging server cookie secrets
+func (c *Client) CookieSecrets(ctx context.Context) ([]response.CookieSecret, error) {
+	raw, err := c.SendCommand(ctx, "print_cookie_secrets")
+	if err != nil {
+		return nil, fmt.Errorf("failed to print cookie secrets: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return nil, fmt.Errorf("failed to print cookie secrets: %w", err)
+	}
+	return parseTraced(ctx, response.ParseCookieSecrets, raw)
+}
+
+// AddCookieSecret adds a staging server cookie secret
+func (c *Client) AddCookieSecret(ctx context.Context, secret string) error {
+	raw, err := c.SendCommand(ctx, fmt.Sprintf("add_cookie_secret %s", secret))
+	if err != nil {
+		return fmt.Errorf("failed to add cookie secret: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to add cookie secret: %w", err)
+	}
+	return nil
+}
+
+// ActivateCookieSecret promotes the staging cookie secret to active
+func (c *Client) ActivateCookieSecret(ctx context.Context) error {
+	raw, err := c.SendCommand(ctx, "activate_cookie_secret")
+	if err != nil {
+		return fmt.Errorf("failed to activate cookie secret: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to activate cookie secret: %w", err)
+	}
+	return nil
+}
+
+// DropCookieSecret removes the staging cookie secret
+func (c *Client) DropCookieSecret(ctx context.Context) error {
+	raw, err := c.SendCommand(ctx, "drop_cookie_secret")
+	if err != nil {
+		return fmt.Errorf("failed to drop cookie secret: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to drop cookie secret: %w", err)
+	}
+	return nil
+}
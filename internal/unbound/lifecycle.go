@@ -0,0 +1,76 @@
+package unbound
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// reloadPollInterval is how often Status is polled while waiting for a reload
+const reloadPollInterval = 250 * time.Millisecond
+
+// ReloadKeepCache reloads the server configuration while preserving the cache
+func (c *Client) ReloadKeepCache(ctx context.Context) error {
+	raw, err := c.SendCommand(ctx, "reload_keep_cache")
+	if err != nil {
+		return fmt.Errorf("failed to reload: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to reload: %w", err)
+	}
+	return nil
+}
+
+// Stop stops the Unbound server. It cannot be started again through the
+// control socket.
+func (c *Client) Stop(ctx context.Context) error {
+	raw, err := c.SendCommand(ctx, "stop")
+	if err != nil {
+		return fmt.Errorf("failed to stop: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to stop: %w", err)
+	}
+	return nil
+}
+
+// WaitForReload polls Status until Unbound reports a version and an uptime
+// showing it restarted after the reload was issued at reloadedAt. before is the
+// status taken just before the reload and may be nil if it was unavailable.
+func (c *Client) WaitForReload(ctx context.Context, before *response.StatusResponse, reloadedAt time.Time, timeout time.Duration) (*response.StatusResponse, error) {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+
+	for {
+		status, err := c.Status(ctx)
+		if err == nil && status.Version != "" {
+			// Without the previous uptime any answer means the server is back
+			if before == nil {
+				return status, nil
+			}
+			// Had the server not restarted, uptime would have kept counting
+			// from its previous value
+			expected := before.Uptime.Seconds + int(time.Since(reloadedAt).Seconds())
+			if status.Uptime.Seconds < expected {
+				return status, nil
+			}
+			lastErr = fmt.Errorf("uptime did not reset (%d seconds)", status.Uptime.Seconds)
+		} else if err != nil {
+			lastErr = err
+		} else {
+			lastErr = fmt.Errorf("no version reported")
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("reload not confirmed within %s: %w", timeout, lastErr)
+		}
+
+		select {
+		case <-time.After(reloadPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package unbound
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// localCommand returns the global or view-scoped variant of a local zone/data
+// command. An empty view selects the global command.
+func localCommand(base, view string, args ...string) string {
+	if view == "" {
+		return strings.Join(append([]string{base}, args...), " ")
+	}
+	return strings.Join(append([]string{"view_" + base, view}, args...), " ")
+}
+
+// checkOK turns an "error ..." reply from unbound-control into a Go error
+func checkOK(raw string) error {
+	if strings.HasPrefix(raw, "error") {
+		return fmt.Errorf("unbound: %s", raw)
+	}
+	return nil
+}
+
+// ListLocalZones returns the local zones, optionally scoped to a view
+func (c *Client) ListLocalZones(ctx context.Context, view string) ([]response.LocalZone, error) {
+	raw, err := c.SendCommand(ctx, localCommand("list_local_zones", view))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local zones: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return nil, fmt.Errorf("failed to list local zones: %w", err)
+	}
+	return parseTraced(ctx, response.ParseLocalZones, raw)
+}
+
+// ListLocalData returns the local data records, optionally scoped to a view
+func (c *Client) ListLocalData(ctx context.Context, view string) ([]response.LocalData, error) {
+	raw, err := c.SendCommand(ctx, localCommand("list_local_data", view))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local data: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return nil, fmt.Errorf("failed to list local data: %w", err)
+	}
+	return parseTraced(ctx, response.ParseLocalData, raw)
+}
+
+// AddLocalZone adds a local zone of the given type, optionally scoped to a view
+func (c *Client) AddLocalZone(ctx context.Context, view, name, zoneType string) error {
+	raw, err := c.SendCommand(ctx, localCommand("local_zone", view, name, zoneType))
+	if err != nil {
+		return fmt.Errorf("failed to add local zone %s: %w", name, err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to add local zone %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveLocalZone removes a local zone, optionally scoped to a view
+func (c *Client) RemoveLocalZone(ctx context.Context, view, name string) error {
+	raw, err := c.SendCommand(ctx, localCommand("local_zone_remove", view, name))
+	if err != nil {
+		return fmt.Errorf("failed to remove local zone %s: %w", name, err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to remove local zone %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddLocalData adds a resource record to local data, optionally scoped to a view
+func (c *Client) AddLocalData(ctx context.Context, view, rr string) error {
+	raw, err := c.SendCommand(ctx, localCommand("local_data", view, rr))
+	if err != nil {
+		return fmt.Errorf("failed to add local data: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to add local data: %w", err)
+	}
+	return nil
+}
+
+// RemoveLocalData removes all local data for a name, optionally scoped to a view
+func (c *Client) RemoveLocalData(ctx context.Context, view, name string) error {
+	raw, err := c.SendCommand(ctx, localCommand("local_data_remove", view, name))
+	if err != nil {
+		return fmt.Errorf("failed to remove local data %s: %w", name, err)
+	}
+	if err := checkOK(raw); err != nil {
+		return fmt.Errorf("failed to remove local data %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package unbound
+
+import (
+	"net"
+	"time"
+)
+
+// Unbound's control protocol carries exactly one command per connection and
+// the server closes the connection once the reply is written, so connections
+// cannot be reused or pipelined. The pool instead keeps a few connections
+// dialed ahead of time so a command only pays for the write and the read.
+// Unbound serves at most 10 control connections at once and times out idle
+// ones after two minutes, so the pool must stay small and short-lived.
+
+// pooledConn is a connection dialed ahead of time, waiting for a command
+type pooledConn struct {
+	net.Conn
+	dialed time.Time
+}
+
+// stale reports whether the connection has been idle too long to be trusted
+func (pc pooledConn) stale(maxIdle time.Duration) bool {
+	return time.Since(pc.dialed) > maxIdle
+}
+
+// getConn returns a pre-dialed connection if one is available, otherwise it dials
+func (c *Client) getConn() (net.Conn, error) {
+	for c.pool != nil {
+		select {
+		case pc := <-c.pool:
+			c.requestRefill()
+			if pc.stale(c.maxIdle) {
+				pc.Close()
+				continue
+			}
+			return pc.Conn, nil
+		default:
+			c.requestRefill()
+			return c.dial()
+		}
+	}
+	return c.dial()
+}
+
+// dial opens a new connection to the control socket
+func (c *Client) dial() (net.Conn, error) {
+	return net.DialTimeout("unix", c.socketPath, c.timeout)
+}
+
+// requestRefill wakes the pool maintainer without blocking
+func (c *Client) requestRefill() {
+	select {
+	case c.refill <- struct{}{}:
+	default:
+	}
+}
+
+// maintainPool keeps the pool filled and evicts stale connections until the
+// client is closed
+func (c *Client) maintainPool() {
+	ticker := time.NewTicker(c.maxIdle / 2)
+	defer ticker.Stop()
+
+	for {
+		c.fillPool()
+
+		select {
+		case <-c.done:
+			c.drainPool()
+			return
+		case <-c.refill:
+		case <-ticker.C:
+			c.evictStale()
+		}
+	}
+}
+
+// fillPool dials until the pool is full, stopping at the first dial error so a
+// missing socket does not cause a busy loop
+func (c *Client) fillPool() {
+	for len(c.pool) < cap(c.pool) {
+		conn, err := c.dial()
+		if err != nil {
+			return
+		}
+		select {
+		case c.pool <- pooledConn{Conn: conn, dialed: time.Now()}:
+		default:
+			conn.Close()
+			return
+		}
+	}
+}
+
+// evictStale closes pooled connections that have been idle too long
+func (c *Client) evictStale() {
+	for i := len(c.pool); i > 0; i-- {
+		select {
+		case pc := <-c.pool:
+			if pc.stale(c.maxIdle) {
+				pc.Close()
+				continue
+			}
+			select {
+			case c.pool <- pc:
+			default:
+				pc.Close()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// drainPool closes every pooled connection
+func (c *Client) drainPool() {
+	for {
+		select {
+		case pc := <-c.pool:
+			pc.Close()
+		default:
+			return
+		}
+	}
+}
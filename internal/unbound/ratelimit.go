@@ -0,0 +1,34 @@
+package unbound
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// RateLimitList returns the domains tracked by Unbound's ratelimit.
+// With all set, the +a option includes domains that are not currently limited.
+func (c *Client) RateLimitList(ctx context.Context, all bool) ([]response.RateLimitEntry, error) {
+	return c.rateLimitList(ctx, "ratelimit_list", all)
+}
+
+// IPRateLimitList returns the client addresses tracked by Unbound's ip-ratelimit.
+// With all set, the +a option includes addresses that are not currently limited.
+func (c *Client) IPRateLimitList(ctx context.Context, all bool) ([]response.RateLimitEntry, error) {
+	return c.rateLimitList(ctx, "ip_ratelimit_list", all)
+}
+
+func (c *Client) rateLimitList(ctx context.Context, cmd string, all bool) ([]response.RateLimitEntry, error) {
+	if all {
+		cmd += " +a"
+	}
+	raw, err := c.SendCommand(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", cmd, err)
+	}
+	if err := checkOK(raw); err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", cmd, err)
+	}
+	return parseTraced(ctx, response.ParseRateLimitList, raw)
+}
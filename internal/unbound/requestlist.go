@@ -0,0 +1,20 @@
+package unbound
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/callMe-Root/unbound-control-api/internal/response"
+)
+
+// RequestList returns the queries Unbound is currently working on
+func (c *Client) RequestList(ctx context.Context) ([]response.RequestListEntry, error) {
+	raw, err := c.SendCommand(ctx, "dump_requestlist")
+	if err != nil {
+		return nil, fmt.Errorf("failed to dump request list: %w", err)
+	}
+	if err := checkOK(raw); err != nil {
+		return nil, fmt.Errorf("failed to dump request list: %w", err)
+	}
+	return parseTraced(ctx, response.ParseRequestList, raw)
+}
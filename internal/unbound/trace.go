@@ -0,0 +1,40 @@
+package unbound
+
+import (
+	"context"
+	"strings"
+
+	"github.com/callMe-Root/unbound-control-api/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// commandName returns the control command without its arguments
+func commandName(cmd string) string {
+	name, _, _ := strings.Cut(cmd, " ")
+	return name
+}
+
+// startSpan starts a child span for one phase of a control command
+func startSpan(ctx context.Context, phase string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "unbound."+phase)
+}
+
+// endSpan records err on span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// parseTraced runs a response parser inside a "parse" span
+func parseTraced[T any](ctx context.Context, parse func(string) (T, error), raw string) (T, error) {
+	_, span := startSpan(ctx, "parse")
+	span.SetAttributes(attribute.Int("unbound.response.bytes", len(raw)))
+	result, err := parse(raw)
+	endSpan(span, err)
+	return result, err
+}
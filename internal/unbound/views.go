@@ -0,0 +1,133 @@
+package unbound
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxIncludeDepth bounds nested include: directives
+const maxIncludeDepth = 16
+
+// ListViews returns the names of the views configured in an unbound.conf file.
+// unbound-control has no command to enumerate views, so they are read from
+// the configuration Unbound was started with. include: and include-toplevel:
+// directives are followed, with relative paths and wildcards resolved against
+// the directory of the file holding the directive.
+func ListViews(configFile string) ([]string, error) {
+	if configFile == "" {
+		return []string{}, nil
+	}
+
+	l := &viewLister{views: []string{}, open: make(map[string]bool)}
+	if err := l.read(configFile, 0); err != nil {
+		return nil, err
+	}
+	return l.views, nil
+}
+
+// viewLister collects view names across a configuration file and the files
+// it includes. inView carries over into included files as Unbound reads them
+// in place of the directive.
+type viewLister struct {
+	views  []string
+	inView bool
+	// open holds the files being read, so an include cycle is reported
+	// rather than followed forever
+	open map[string]bool
+}
+
+func (l *viewLister) read(path string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("unbound config includes are nested more than %d deep at %s", maxIncludeDepth, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to open unbound config: %w", err)
+	}
+	if l.open[abs] {
+		return fmt.Errorf("unbound config %s includes itself", path)
+	}
+	l.open[abs] = true
+	defer delete(l.open, abs)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open unbound config: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// Clauses such as "server:" or "view:" have nothing after the colon
+		if strings.HasSuffix(line, ":") && !strings.Contains(line, " ") {
+			l.inView = line == "view:"
+			continue
+		}
+
+		if pattern, ok := directive(line, "include:"); ok {
+			if err := l.include(path, pattern, depth); err != nil {
+				return err
+			}
+			continue
+		}
+		// Files included at the top level start outside any clause and end
+		// the clause the directive appeared in
+		if pattern, ok := directive(line, "include-toplevel:"); ok {
+			l.inView = false
+			if err := l.include(path, pattern, depth); err != nil {
+				return err
+			}
+			l.inView = false
+			continue
+		}
+
+		if name, ok := directive(line, "name:"); ok && l.inView {
+			l.views = append(l.views, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading unbound config: %w", err)
+	}
+	return nil
+}
+
+// include reads the files matching pattern, which may contain wildcards
+func (l *viewLister) include(from, pattern string, depth int) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(from), pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid include in %s: %w", from, err)
+	}
+	// A wildcard may match nothing, a plain file name must exist
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		matches = []string{pattern}
+	}
+	for _, match := range matches {
+		if err := l.read(match, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// directive returns the unquoted value of line if it starts with key
+func directive(line, key string) (string, bool) {
+	if !strings.HasPrefix(line, key) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, key)), `"`), true
+}
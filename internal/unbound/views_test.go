@@ -0,0 +1,88 @@
+package unbound
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeConfig writes files, keyed by name relative to a new directory, and
+// returns the path of main
+func writeConfig(t *testing.T, main string, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	return filepath.Join(dir, main)
+}
+
+func TestListViews(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{"single file", map[string]string{
+			"unbound.conf": "server:\n  verbosity: 1\nview:\n  name: \"internal\" # comment\n  local-zone: example.com. static\nview:\n  name: guest\n",
+		}, []string{"internal", "guest"}},
+		{"include", map[string]string{
+			"unbound.conf":        "server:\n  verbosity: 1\ninclude: \"views/internal.conf\"\n",
+			"views/internal.conf": "view:\n  name: internal\n",
+		}, []string{"internal"}},
+		{"include inside a view clause", map[string]string{
+			"unbound.conf":   "view:\n  include: view-name.conf\n",
+			"view-name.conf": "  name: internal\n",
+		}, []string{"internal"}},
+		{"wildcard include", map[string]string{
+			"unbound.conf": "include: \"views/*.conf\"\ninclude: \"empty/*.conf\"\n",
+			"views/a.conf": "view:\n  name: a\n",
+			"views/b.conf": "view:\n  name: b\n",
+		}, []string{"a", "b"}},
+		{"include-toplevel ends the clause", map[string]string{
+			"unbound.conf": "view:\n  name: a\ninclude-toplevel: \"server.conf\"\n  name: not-a-view\n",
+			"server.conf":  "server:\n  name: not-a-view-either\n",
+		}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			views, err := ListViews(writeConfig(t, "unbound.conf", tt.files))
+			if err != nil {
+				t.Fatalf("ListViews: %v", err)
+			}
+			if !reflect.DeepEqual(views, tt.want) {
+				t.Errorf("ListViews = %q, want %q", views, tt.want)
+			}
+		})
+	}
+}
+
+func TestListViewsIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"missing include", map[string]string{
+			"unbound.conf": "include: \"missing.conf\"\n",
+		}},
+		{"include cycle", map[string]string{
+			"unbound.conf": "include: \"a.conf\"\n",
+			"a.conf":       "include: \"unbound.conf\"\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ListViews(writeConfig(t, "unbound.conf", tt.files)); err == nil {
+				t.Fatal("ListViews succeeded")
+			}
+		})
+	}
+}
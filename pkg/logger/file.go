@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"io"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// FileConfig configures the rotating log file sink
+type FileConfig struct {
+	// Path of the active log file, rotated files are kept next to it
+	Path string
+	// MaxSizeMB rotates the file once it reaches this size
+	MaxSizeMB int
+	// RotateInterval also rotates the file after this long, 0 disables it
+	RotateInterval time.Duration
+	// MaxAge removes rotated files older than this, 0 keeps them
+	MaxAge time.Duration
+	// MaxBackups limits the number of rotated files kept, 0 keeps all
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// newFileWriter opens the rotating file sink described by cfg
+func newFileWriter(cfg FileConfig) io.Writer {
+	// lumberjack expresses retention in whole days
+	maxAgeDays := 0
+	if cfg.MaxAge > 0 {
+		maxAgeDays = int((cfg.MaxAge + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     maxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+		LocalTime:  true,
+	}
+
+	// lumberjack only rotates on size, so rotate on a timer as well
+	if cfg.RotateInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.RotateInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				file.Rotate()
+			}
+		}()
+	}
+
+	return file
+}
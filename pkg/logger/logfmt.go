@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// logfmtWriter converts zerolog's JSON events into logfmt lines
+type logfmtWriter struct {
+	out io.Writer
+}
+
+// leadingKeys are written first, in this order, when present
+var leadingKeys = []string{zerolog.TimestampFieldName, zerolog.LevelFieldName, zerolog.MessageFieldName}
+
+// Write implements io.Writer
+func (w logfmtWriter) Write(p []byte) (int, error) {
+	var event map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(p))
+	decoder.UseNumber()
+	if err := decoder.Decode(&event); err != nil {
+		// Not an event we can convert, pass it through untouched
+		return w.out.Write(p)
+	}
+
+	var buf bytes.Buffer
+	for _, key := range leadingKeys {
+		if value, ok := event[key]; ok {
+			writePair(&buf, key, value)
+			delete(event, key)
+		}
+	}
+
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writePair(&buf, key, event[key])
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writePair appends key=value, quoting the value when needed
+func writePair(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case json.Number:
+		buf.WriteString(v.String())
+		return
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+		return
+	case nil:
+		return
+	default:
+		// Nested objects and arrays are kept as compact JSON
+		encoded, _ := json.Marshal(v)
+		s = string(encoded)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		s = strconv.Quote(s)
+	}
+	buf.WriteString(s)
+}
@@ -1,18 +1,54 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/syslog"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
-// Initialize sets up the global logger
-func Initialize(level string, useSyslog bool, appName string) {
+// Supported output formats
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+	FormatLogfmt  = "logfmt"
+)
+
+// Supported outputs
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	OutputSyslog = "syslog"
+	OutputFile   = "file"
+)
+
+// Config configures the global logger
+type Config struct {
+	// Level is the minimum level logged
+	Level string
+	// Format is console, json or logfmt and applies to every output
+	Format string
+	// Outputs lists where logs are written: stdout, stderr, syslog and file
+	Outputs []string
+	// UseSyslog adds the syslog output, kept for older configurations
+	UseSyslog bool
+	// AppName is the syslog tag
+	AppName string
+	// File configures the file output
+	File FileConfig
+}
+
+// Initialize sets up the global logger. Outputs that fail to open are
+// reported through the logger; if none can be opened, logs go to stderr.
+func Initialize(cfg Config) {
 	// Parse log level
-	logLevel, err := zerolog.ParseLevel(level)
+	logLevel, err := zerolog.ParseLevel(cfg.Level)
 	if err != nil {
 		logLevel = zerolog.InfoLevel
 	}
@@ -21,39 +57,109 @@ func Initialize(level string, useSyslog bool, appName string) {
 	zerolog.SetGlobalLevel(logLevel)
 	zerolog.TimeFieldFormat = time.RFC3339
 
-	var output zerolog.ConsoleWriter
-	if useSyslog {
-		// Create syslog writer
-		syslogWriter, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, appName)
+	format := cfg.Format
+	if format == "" {
+		format = FormatConsole
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 && !cfg.UseSyslog {
+		outputs = []string{OutputStdout}
+	}
+	if cfg.UseSyslog && !slices.Contains(outputs, OutputSyslog) {
+		outputs = append(outputs, OutputSyslog)
+	}
+
+	var writers []io.Writer
+	var setupErrors []error
+	for _, output := range outputs {
+		w, err := newOutput(output, format, cfg)
 		if err != nil {
-			// Fallback to console if syslog fails
-			output = zerolog.ConsoleWriter{
-				Out:        os.Stderr,
-				TimeFormat: time.RFC3339,
-			}
-			log.Logger = zerolog.New(output).With().Timestamp().Logger()
-			log.Error().Err(err).Msg("Failed to initialize syslog, falling back to console output")
-			return
+			setupErrors = append(setupErrors, err)
+			continue
 		}
+		writers = append(writers, w)
+	}
 
-		// Create zerolog writer that writes to syslog
-		output = zerolog.ConsoleWriter{
-			Out:        syslogWriter,
-			TimeFormat: time.RFC3339,
-			NoColor:    true,
+	if len(writers) == 0 {
+		// Fallback to console if no output could be opened
+		writers = append(writers, formatWriter(os.Stderr, format, true))
+	}
+
+	log.Logger = zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp().Logger()
+	for _, err := range setupErrors {
+		log.Error().Err(err).Msg("Failed to initialize log output")
+	}
+}
+
+// newOutput opens a single output and wraps it in the configured format
+func newOutput(output, format string, cfg Config) (io.Writer, error) {
+	switch output {
+	case OutputStdout:
+		return formatWriter(os.Stdout, format, true), nil
+	case OutputStderr:
+		return formatWriter(os.Stderr, format, true), nil
+	case OutputSyslog:
+		syslogWriter, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, cfg.AppName)
+		if err != nil {
+			return nil, fmt.Errorf("syslog: %w", err)
 		}
-	} else {
-		// Use console writer for development
-		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: time.RFC3339,
+		// JSON events keep their severity in syslog, other formats are
+		// written as plain lines at the default priority
+		if format == FormatJSON {
+			return zerolog.SyslogLevelWriter(syslogWriter), nil
+		}
+		return formatWriter(syslogWriter, format, false), nil
+	case OutputFile:
+		if cfg.File.Path == "" {
+			return nil, fmt.Errorf("file: no path configured")
 		}
+		return formatWriter(newFileWriter(cfg.File), format, false), nil
+	default:
+		return nil, fmt.Errorf("unknown log output %q", output)
 	}
+}
 
-	log.Logger = zerolog.New(output).With().Timestamp().Logger()
+// formatWriter wraps out so events are written in format. Colors are only
+// used for console output to stdout or stderr.
+func formatWriter(out io.Writer, format string, color bool) io.Writer {
+	switch format {
+	case FormatJSON:
+		return out
+	case FormatLogfmt:
+		return logfmtWriter{out: out}
+	default:
+		return zerolog.ConsoleWriter{
+			Out:        out,
+			TimeFormat: time.RFC3339,
+			NoColor:    !color,
+		}
+	}
 }
 
 // Get returns the global logger instance
 func Get() *zerolog.Logger {
 	return &log.Logger
 }
+
+// requestIDKey is the context key for the request ID
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns l with the request ID from ctx attached, if there is one
+func FromContext(ctx context.Context, l zerolog.Logger) zerolog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return l.With().Str("request_id", id).Logger()
+	}
+	return l
+}